@@ -0,0 +1,231 @@
+package logger
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"sync"
+)
+
+// AdminHandler is an http.Handler to inspect and change the
+// configuration of registered loggers at runtime. All responses are
+// JSON. A GET request reports the status of every logger, POST requests
+// to paths ending in the following names change it:
+//
+//	level   {"logger": "name", "level": "debug"}
+//	file    {"logger": "name", "size_limit": 1048576, "max_files": 5}
+//	rotate  {"logger": "name"}
+//	reopen  {"logger": "name"}
+//
+// An empty logger name applies the change to all loggers. When rotating
+// or reopening fails for some loggers, the others are still processed
+// and the failures are listed by logger name under "errors".
+type AdminHandler struct {
+	sync.Mutex
+	loggers   map[string]*Logger
+	authorize func(r *http.Request) bool
+}
+
+// Limit for the size of admin request bodies.
+const maxAdminRequestSize = 64 << 10
+
+type adminRequest struct {
+	Logger    string `json:"logger"`
+	Level     string `json:"level"`
+	SizeLimit *int64 `json:"size_limit"`
+	MaxFiles  *int   `json:"max_files"`
+}
+
+type adminFileStatus struct {
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	SizeLimit int64  `json:"size_limit"`
+	MaxFiles  int    `json:"max_files"`
+}
+
+type adminLoggerStatus struct {
+	Level string           `json:"level"`
+	Sinks []string         `json:"sinks"`
+	File  *adminFileStatus `json:"file,omitempty"`
+}
+
+// NewAdminHandler creates an admin handler. Every request must be
+// accepted by authorize; when it is nil, only GET requests are served.
+func NewAdminHandler(authorize func(r *http.Request) bool) *AdminHandler {
+	return &AdminHandler{
+		loggers:   make(map[string]*Logger),
+		authorize: authorize,
+	}
+}
+
+// AdminBearerToken returns an authorization function accepting requests
+// with an "Authorization: Bearer <token>" header.
+func AdminBearerToken(token string) func(r *http.Request) bool {
+	want := []byte("Bearer " + token)
+	return func(r *http.Request) bool {
+		return subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) == 1
+	}
+}
+
+// Register makes a logger available under the given name.
+func (h *AdminHandler) Register(name string, l *Logger) {
+	h.Lock()
+	defer h.Unlock()
+	h.loggers[name] = l
+}
+
+// ServeHTTP implements http.Handler.
+func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.authorize != nil && !h.authorize(r) {
+		writeAdminError(w, http.StatusUnauthorized, fmt.Errorf("unauthorized"))
+		return
+	}
+	if r.Method == http.MethodGet {
+		writeAdminJSON(w, http.StatusOK, map[string]any{"loggers": h.status()})
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeAdminError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	if h.authorize == nil {
+		writeAdminError(w, http.StatusForbidden, fmt.Errorf("changes are not allowed without authorization"))
+		return
+	}
+
+	var req adminRequest
+	body := http.MaxBytesReader(w, r.Body, maxAdminRequestSize)
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		writeAdminError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+	loggers, err := h.lookup(req.Logger)
+	if err != nil {
+		writeAdminError(w, http.StatusNotFound, err)
+		return
+	}
+
+	resp := map[string]any{}
+	errs := map[string]string{}
+	switch action := path.Base(r.URL.Path); action {
+	case "level":
+		level, err := ParseLevel(req.Level)
+		if err != nil {
+			writeAdminError(w, http.StatusBadRequest, err)
+			return
+		}
+		for _, l := range loggers {
+			l.SetLevel(level)
+		}
+	case "file":
+		if (req.SizeLimit != nil && *req.SizeLimit <= 0) || (req.MaxFiles != nil && *req.MaxFiles < 0) {
+			writeAdminError(w, http.StatusBadRequest, fmt.Errorf("size_limit must be positive and max_files must not be negative"))
+			return
+		}
+		for name, l := range loggers {
+			if l.fl == nil {
+				writeAdminError(w, http.StatusBadRequest, fmt.Errorf("logger %q has no log file", name))
+				return
+			}
+		}
+		for _, l := range loggers {
+			if req.SizeLimit != nil {
+				l.SetSizeLimit(*req.SizeLimit)
+			}
+			if req.MaxFiles != nil {
+				l.SetMaxNumFiles(*req.MaxFiles)
+			}
+		}
+	case "rotate":
+		backups := map[string]string{}
+		for name, l := range loggers {
+			if l.fl == nil {
+				continue
+			}
+			bak, err := l.Rotate()
+			if err != nil {
+				errs[name] = fmt.Sprintf("failed to rotate: %v", err)
+				continue
+			}
+			backups[name] = bak
+		}
+		resp["backups"] = backups
+	case "reopen":
+		for name, l := range loggers {
+			if l.fl == nil {
+				continue
+			}
+			if err := l.Reopen(); err != nil {
+				errs[name] = fmt.Sprintf("failed to reopen: %v", err)
+			}
+		}
+	default:
+		writeAdminError(w, http.StatusNotFound, fmt.Errorf("unknown action %q", action))
+		return
+	}
+	resp["loggers"] = h.status()
+	code := http.StatusOK
+	if len(errs) > 0 {
+		resp["errors"] = errs
+		code = http.StatusInternalServerError
+	}
+	writeAdminJSON(w, code, resp)
+}
+
+// lookup returns the named logger, or all loggers for an empty name.
+func (h *AdminHandler) lookup(name string) (map[string]*Logger, error) {
+	h.Lock()
+	defer h.Unlock()
+	if name == "" {
+		all := make(map[string]*Logger, len(h.loggers))
+		for n, l := range h.loggers {
+			all[n] = l
+		}
+		return all, nil
+	}
+	l, ok := h.loggers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown logger %q", name)
+	}
+	return map[string]*Logger{name: l}, nil
+}
+
+func (h *AdminHandler) status() map[string]adminLoggerStatus {
+	loggers, _ := h.lookup("")
+	status := make(map[string]adminLoggerStatus, len(loggers))
+	for name, l := range loggers {
+		l.Lock()
+		sinks, fl := l.sinks, l.fl
+		l.Unlock()
+		st := adminLoggerStatus{Level: l.Level().String(), Sinks: []string{}}
+		for _, s := range sinks {
+			st.Sinks = append(st.Sinks, fmt.Sprintf("%T", s))
+		}
+		sort.Strings(st.Sinks)
+		if fl != nil {
+			fl.Lock()
+			st.File = &adminFileStatus{
+				Path:      fl.file.Name(),
+				Size:      fl.currentSize,
+				SizeLimit: fl.originalRotationLimit,
+				MaxFiles:  fl.maxBackupFiles,
+			}
+			fl.Unlock()
+		}
+		status[name] = st
+	}
+	return status
+}
+
+func writeAdminJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAdminError(w http.ResponseWriter, code int, err error) {
+	writeAdminJSON(w, code, map[string]string{"error": err.Error()})
+}
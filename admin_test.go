@@ -0,0 +1,177 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type adminResponse struct {
+	Loggers map[string]adminLoggerStatus `json:"loggers"`
+	Backups map[string]string            `json:"backups"`
+	Errors  map[string]string            `json:"errors"`
+	Error   string                       `json:"error"`
+}
+
+func adminDo(t *testing.T, h http.Handler, method, target, token, body string) (int, adminResponse) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var resp adminResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Invalid JSON response %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, resp
+}
+
+func TestAdminHandler(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.log")
+	app := NewFileLogger(file, false, false, false, false)
+	defer app.Close()
+	app.SetSizeLimit(1 << 20)
+	app.Noticef("hello")
+	audit := NewSinkLogger(false, false, &testSink{})
+
+	h := NewAdminHandler(AdminBearerToken("s3cret"))
+	h.Register("app", app)
+	h.Register("audit", audit)
+
+	code, resp := adminDo(t, h, "GET", "/admin/log", "s3cret", "")
+	if code != http.StatusOK {
+		t.Fatalf("Unexpected status %d: %s", code, resp.Error)
+	}
+	st := resp.Loggers["app"]
+	if st.Level != "info" || st.File == nil || st.File.Path != file || st.File.Size == 0 || st.File.SizeLimit != 1<<20 {
+		t.Errorf("Unexpected app status %+v %+v", st, st.File)
+	}
+	if sinks := resp.Loggers["audit"].Sinks; len(sinks) != 1 || sinks[0] != "*logger.testSink" {
+		t.Errorf("Unexpected audit sinks %v", sinks)
+	}
+
+	// Per named logger, then globally.
+	code, resp = adminDo(t, h, "POST", "/admin/log/level", "s3cret", `{"logger": "audit", "level": "debug"}`)
+	if code != http.StatusOK || resp.Loggers["audit"].Level != "debug" || resp.Loggers["app"].Level != "info" {
+		t.Errorf("Unexpected response to level change: %d %+v", code, resp)
+	}
+	adminDo(t, h, "POST", "/admin/log/level", "s3cret", `{"level": "trace"}`)
+	if !app.trace.Load() || !audit.trace.Load() {
+		t.Error("Expected trace to be enabled on all loggers")
+	}
+
+	code, resp = adminDo(t, h, "POST", "/admin/log/file", "s3cret", `{"logger": "app", "size_limit": 2048, "max_files": 3}`)
+	if code != http.StatusOK || resp.Loggers["app"].File.SizeLimit != 2048 || resp.Loggers["app"].File.MaxFiles != 3 {
+		t.Errorf("Unexpected response to file change: %d %+v", code, resp)
+	}
+
+	for _, body := range []string{`{"logger": "app", "size_limit": 0}`, `{"logger": "app", "max_files": -1}`} {
+		if code, _ := adminDo(t, h, "POST", "/admin/log/file", "s3cret", body); code != http.StatusBadRequest {
+			t.Errorf("Expected invalid file settings %s to be rejected, got %d", body, code)
+		}
+	}
+
+	code, resp = adminDo(t, h, "POST", "/admin/log/rotate", "s3cret", `{"logger": "app"}`)
+	bak := resp.Backups["app"]
+	if code != http.StatusOK || !strings.HasPrefix(bak, file+".") {
+		t.Fatalf("Unexpected response to rotation: %d %+v", code, resp)
+	}
+	if content, err := os.ReadFile(bak); err != nil || !strings.Contains(string(content), "hello") {
+		t.Errorf("Expected backup to hold the old entries, got %q (%v)", content, err)
+	}
+
+	// Reopen after an external tool moved the file.
+	os.Rename(file, file+".moved")
+	if code, resp = adminDo(t, h, "POST", "/admin/log/reopen", "s3cret", `{}`); code != http.StatusOK {
+		t.Fatalf("Unexpected reopen status %d: %s", code, resp.Error)
+	}
+	app.Noticef("after reopen")
+	if content, err := os.ReadFile(file); err != nil || !strings.Contains(string(content), "after reopen") {
+		t.Errorf("Expected a new log file after reopen, got %q (%v)", content, err)
+	}
+}
+
+func TestAdminHandler_Errors(t *testing.T) {
+	l := NewSinkLogger(false, false)
+	h := NewAdminHandler(AdminBearerToken("s3cret"))
+	h.Register("app", l)
+
+	tests := []struct {
+		method, target, token, body string
+		code                        int
+	}{
+		{"GET", "/", "wrong", "", http.StatusUnauthorized},
+		{"POST", "/level", "s3cret", `{"level": "loud"}`, http.StatusBadRequest},
+		{"POST", "/level", "s3cret", `{"logger": "other", "level": "info"}`, http.StatusNotFound},
+		{"POST", "/file", "s3cret", `{"size_limit": 10}`, http.StatusBadRequest},
+		{"POST", "/explode", "s3cret", `{}`, http.StatusNotFound},
+		{"POST", "/level", "s3cret", `{"level": "` + strings.Repeat("x", maxAdminRequestSize) + `"}`, http.StatusBadRequest},
+		{"DELETE", "/", "s3cret", "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		if code, resp := adminDo(t, h, tt.method, tt.target, tt.token, tt.body); code != tt.code || resp.Error == "" {
+			t.Errorf("%s %s %s: expected error status %d, got %d %+v", tt.method, tt.target, tt.body, tt.code, code, resp)
+		}
+	}
+
+	readOnly := NewAdminHandler(nil)
+	readOnly.Register("app", l)
+	if code, _ := adminDo(t, readOnly, "GET", "/", "", ""); code != http.StatusOK {
+		t.Errorf("Expected reads without authorization, got %d", code)
+	}
+	if code, _ := adminDo(t, readOnly, "POST", "/level", "", `{"level": "debug"}`); code != http.StatusForbidden {
+		t.Errorf("Expected changes to be forbidden without authorization, got %d", code)
+	}
+}
+
+func TestLoggerSetLevel(t *testing.T) {
+	sink := &testSink{}
+	l := NewSinkLogger(true, true, sink)
+	if l.Level() != LevelTrace {
+		t.Errorf("Expected trace level, got %v", l.Level())
+	}
+	l.SetLevel(LevelWarn)
+	l.Debugf("debug")
+	l.Noticef("info")
+	l.Warnf("warn")
+	if entries := sink.Entries(); len(entries) != 1 || entries[0].Message != "warn" || l.Level() != LevelWarn {
+		t.Errorf("Expected only warnings to be logged, got %d entries", len(entries))
+	}
+	if err := l.SetLevel(Level(42)); err == nil {
+		t.Error("Expected error for invalid level")
+	}
+	if lvl, err := ParseLevel("ERROR"); err != nil || lvl != LevelError {
+		t.Errorf("Unexpected ParseLevel result %v %v", lvl, err)
+	}
+}
+
+func TestAdminHandler_PartialRotate(t *testing.T) {
+	dir := t.TempDir()
+	good := NewFileLogger(filepath.Join(dir, "good.log"), false, false, false, false)
+	defer good.Close()
+	bad := NewFileLogger(filepath.Join(dir, "bad.log"), false, false, false, false)
+	defer bad.Close()
+	if err := os.Remove(filepath.Join(dir, "bad.log")); err != nil {
+		t.Fatalf("Failed to remove log file: %v", err)
+	}
+
+	h := NewAdminHandler(AdminBearerToken("s3cret"))
+	h.Register("good", good)
+	h.Register("bad", bad)
+	code, resp := adminDo(t, h, "POST", "/rotate", "s3cret", `{}`)
+	if code != http.StatusInternalServerError {
+		t.Fatalf("Expected partial failure status, got %d", code)
+	}
+	if resp.Backups["good"] == "" || resp.Errors["bad"] == "" || resp.Errors["good"] != "" {
+		t.Errorf("Expected the good logger rotated and the bad one reported, got %+v", resp)
+	}
+	if len(resp.Loggers) != 2 {
+		t.Errorf("Expected the status of all loggers, got %+v", resp.Loggers)
+	}
+}
@@ -0,0 +1,46 @@
+package logger
+
+import "time"
+
+// Clock is the source of the current time for a logger.
+type Clock interface {
+	Now() time.Time
+}
+
+// LogClock sets the clock a logger reads the time from, for timestamps,
+// entry times, backup file names and time-based rules such as sampling
+// and fallback probing, instead of the system clock. This makes output
+// predictable in tests. Intervals of background flushing and summaries
+// still follow the system clock.
+type LogClock struct {
+	Clock Clock
+}
+
+func (c LogClock) isLoggerOption() {}
+
+// clockOption returns the clock set in opts, or nil for the system clock.
+func clockOption(opts ...LogOption) Clock {
+	var c Clock
+	for _, opt := range opts {
+		if v, ok := opt.(LogClock); ok {
+			c = v.Clock
+		}
+	}
+	return c
+}
+
+// clockNow reads c, or the system clock when c is nil.
+func clockNow(c Clock) time.Time {
+	if c == nil {
+		return time.Now()
+	}
+	return c.Now()
+}
+
+func (l *Logger) now() time.Time {
+	return clockNow(l.clock)
+}
+
+func (fl *FileLogger) now() time.Time {
+	return clockNow(fl.clock)
+}
@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Helper clock that only moves when advanced.
+type fakeClock struct {
+	sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestLoggerClock(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 3, 5, 7, 8, 9, 123456789, time.UTC)}
+	file := filepath.Join(t.TempDir(), "test.log")
+	l := NewFileLogger(file, true, false, false, false, LogClock{clock}, LogUTC(true))
+	defer l.Close()
+	sink := &testSink{}
+	l.AddSink(sink)
+
+	l.Noticef("before rotation")
+	clock.Advance(time.Second)
+	bak, err := l.Rotate()
+	if err != nil {
+		t.Fatalf("Failed to rotate: %v", err)
+	}
+
+	if want := file + ".2024.03.05.07.08.10.123456789"; bak != want {
+		t.Errorf("Expected backup %q, got %q", want, bak)
+	}
+	if content := readLog(t, bak); content != "2024/03/05 07:08:09.123456 [INF] before rotation\n" {
+		t.Errorf("Unexpected backup content %q", content)
+	}
+	want := "2024/03/05 07:08:10.123456 [INF] Rotated log, backup saved as \"" + bak + "\"\n"
+	if content := readLog(t, file); content != want {
+		t.Errorf("Expected %q, got %q", want, content)
+	}
+	if entries := sink.Entries(); len(entries) != 1 || !entries[0].Time.Equal(clock.Now().Add(-time.Second)) {
+		t.Errorf("Expected entry time from the clock, got %v", entries)
+	}
+}
+
+func TestLoggerClockSampling(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	file := filepath.Join(t.TempDir(), "test.log")
+	l := NewFileLogger(file, false, false, false, false, LogClock{clock})
+	if err := l.SetSampling(LevelInfo, SamplingPolicy{First: 1, Interval: time.Minute}); err != nil {
+		t.Fatalf("Failed to set sampling: %v", err)
+	}
+
+	l.Noticef("sampled")
+	l.Noticef("sampled")
+	clock.Advance(time.Minute)
+	l.Noticef("sampled")
+	l.Close()
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	want := "[INF] sampled\n[INF] sampled\n[INF] suppressed 1 similar messages: \"sampled\"\n"
+	if string(content) != want {
+		t.Errorf("Expected a new sampling interval from the clock, got %q", content)
+	}
+}
@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// deduper collapses consecutive identical messages, like syslogd's
+// "last message repeated N times". The first message is written, its
+// repeats are counted and reported through emit when a different
+// message arrives, when the window expires, or on flush. With an
+// injected clock there is no timer, the window is checked when the
+// next message arrives.
+type deduper struct {
+	sync.Mutex
+	window   time.Duration
+	clock    Clock
+	emit     func(level Level, msg string, repeated int)
+	level    Level
+	msg      string
+	last     bool
+	repeated int
+	since    time.Time // of the first repeat
+	timer    *time.Timer
+}
+
+func newDeduper(window time.Duration, clock Clock, emit func(level Level, msg string, repeated int)) *deduper {
+	return &deduper{window: window, clock: clock, emit: emit}
+}
+
+// repeatMessage is the text logged in place of collapsed repeats.
+func repeatMessage(repeated int) string {
+	return fmt.Sprintf("last message repeated %d times", repeated)
+}
+
+// check reports whether a message must be written, it is false for a
+// repeat of the previous message.
+func (d *deduper) check(level Level, msg string) bool {
+	now := clockNow(d.clock)
+	d.Lock()
+	defer d.Unlock()
+	if d.last && level == d.level && msg == d.msg {
+		if d.repeated > 0 && now.Sub(d.since) >= d.window {
+			d.flushLocked()
+		}
+		if d.repeated == 0 {
+			d.since = now
+		}
+		d.repeated++
+		if d.timer == nil && d.clock == nil {
+			d.timer = time.AfterFunc(d.window, d.flush)
+		}
+		return false
+	}
+	d.flushLocked()
+	d.level, d.msg, d.last = level, msg, true
+	return true
+}
+
+// flush reports pending repeats. The message stays current, so that
+// further repeats are collapsed into a new report.
+func (d *deduper) flush() {
+	d.Lock()
+	defer d.Unlock()
+	d.flushLocked()
+}
+
+// flushLocked is flush with the lock held, which keeps the report
+// ordered before the message that triggered it.
+func (d *deduper) flushLocked() {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	if d.repeated > 0 {
+		d.emit(d.level, d.msg, d.repeated)
+		d.repeated = 0
+	}
+}
+
+// SetDedup collapses consecutive identical messages of the same level
+// into the first one and a "last message repeated N times" entry,
+// written at the latest one window after the first repeat. A zero
+// window disables it. Loggers derived with WithFields after this call
+// share the state, as they share the output.
+func (l *Logger) SetDedup(window time.Duration) error {
+	if window < 0 {
+		return fmt.Errorf("dedup window must not be negative")
+	}
+	l.Lock()
+	old := l.dedup
+	l.dedup = nil
+	if window > 0 {
+		l.dedup = newDeduper(window, l.clock, func(level Level, msg string, repeated int) {
+			l.output(level, l.label(level), repeatMessage(repeated), Fields{"repeated": repeated})
+		})
+	}
+	l.Unlock()
+	if old != nil {
+		old.flush()
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoggerDedup(t *testing.T) {
+	sink := &testSink{}
+	l := NewSinkLogger(false, false, sink)
+	if err := l.SetDedup(time.Hour); err != nil {
+		t.Fatalf("Failed to set dedup: %v", err)
+	}
+
+	for i := 0; i < 4; i++ {
+		l.Warnf("disk %s almost full", "/var")
+	}
+	l.Errorf("disk %s almost full", "/var") // same text, different level
+	l.Errorf("disk %s almost full", "/var")
+	l.Noticef("done")
+
+	var got []string
+	for _, e := range sink.Entries() {
+		got = append(got, e.Level.String()+":"+e.Message)
+	}
+	want := []string{
+		"warn:disk /var almost full",
+		"warn:last message repeated 3 times",
+		"error:disk /var almost full",
+		"error:last message repeated 1 times",
+		"info:done",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Unexpected entries:\n got %v\nwant %v", got, want)
+	}
+	if n := sink.Entries()[1].Fields["repeated"]; n != 3 {
+		t.Errorf("Expected repeated field 3, got %v", n)
+	}
+
+	l.Noticef("done")
+	l.Close()
+	entries := sink.Entries()
+	if last := entries[len(entries)-1]; last.Message != "last message repeated 1 times" {
+		t.Errorf("Expected Close to flush pending repeats, got %q", last.Message)
+	}
+}
+
+func TestLoggerDedup_Timer(t *testing.T) {
+	sink := &testSink{}
+	l := NewSinkLogger(false, false, sink)
+	defer l.Close()
+	l.SetDedup(30 * time.Millisecond)
+
+	l.Noticef("again")
+	l.Noticef("again")
+	l.Noticef("again")
+	time.Sleep(60 * time.Millisecond)
+	if entries := sink.Entries(); len(entries) != 2 || entries[1].Message != "last message repeated 2 times" {
+		t.Fatalf("Expected the timer to flush repeats, got %d entries", len(entries))
+	}
+
+	// Later repeats of the same message are counted again.
+	l.Noticef("again")
+	time.Sleep(60 * time.Millisecond)
+	if entries := sink.Entries(); len(entries) != 3 || entries[2].Message != "last message repeated 1 times" {
+		t.Errorf("Expected a second repeat report, got %d entries", len(entries))
+	}
+}
+
+func TestLoggerDedup_Clock(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	file := filepath.Join(t.TempDir(), "test.log")
+	l := NewFileLogger(file, false, false, false, false, LogClock{clock})
+	defer l.Close()
+	l.SetDedup(time.Minute)
+
+	l.Noticef("again")
+	l.Noticef("again")
+	clock.Advance(time.Minute)
+	l.Noticef("again")
+	l.Noticef("again")
+	l.flushBeforeExit(time.Second)
+
+	content, _ := os.ReadFile(file)
+	want := "[INF] again\n[INF] last message repeated 1 times\n[INF] last message repeated 2 times\n"
+	if string(content) != want {
+		t.Errorf("Expected the clock to expire the window and the exit flush to report repeats, got %q", content)
+	}
+}
+
+func TestFileLoggerDedup(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "dedup.log")
+	l := NewFileLogger(file, false, false, false, false)
+	l.SetDedup(time.Hour)
+
+	for i := 0; i < 3; i++ {
+		l.Errorf("connection refused")
+	}
+	l.Noticef("recovered")
+	l.Close()
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Error reading log file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[1], "[ERR] last message repeated 2 times") ||
+		!strings.Contains(lines[2], "[INF] recovered") {
+		t.Errorf("Unexpected log file content:\n%s", content)
+	}
+}
+
+func TestSysLoggerDedup(t *testing.T) {
+	addr, ch := newTestUDPSyslog(t)
+	sl, err := NewSysLogger(addr, false, false)
+	if err != nil {
+		t.Fatalf("Failed to create remote syslogger: %v", err)
+	}
+	if err := sl.SetDedup(time.Hour); err != nil {
+		t.Fatalf("Failed to set dedup: %v", err)
+	}
+
+	sl.Warnf("flapping")
+	sl.Warnf("flapping")
+	sl.Warnf("flapping")
+	expectSyslogMsg(t, ch, "flapping")
+	expectNoSyslogMsg(t, ch)
+
+	sl.Close()
+	expectSyslogMsg(t, ch, "last message repeated 2 times")
+}
+
+func TestSetDedup_Negative(t *testing.T) {
+	l := NewSinkLogger(false, false)
+	if err := l.SetDedup(-time.Second); err == nil {
+		t.Error("Expected error for negative window")
+	}
+}
@@ -0,0 +1,141 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ElasticsearchSink indexes entries through the Elasticsearch _bulk API.
+// The index name is a template in which text between braces is a Go time
+// layout applied to the entry's UTC timestamp, e.g. "logs-{2006.01.02}"
+// writes to a daily index. Entries rejected with 429 or 5xx item
+// statuses are retried, other rejections are reported and dropped.
+type ElasticsearchSink struct {
+	*HTTPSink
+}
+
+type esEncoder struct {
+	index string
+}
+
+// NewElasticsearchSink creates a sink for the cluster at baseURL
+// (e.g. "http://localhost:9200"), "/_bulk" is appended unless the URL
+// already points to a bulk endpoint.
+func NewElasticsearchSink(baseURL, indexTemplate string) (*ElasticsearchSink, error) {
+	if indexTemplate == "" {
+		return nil, fmt.Errorf("elasticsearch index must not be empty")
+	}
+	if strings.Count(indexTemplate, "{") != strings.Count(indexTemplate, "}") {
+		return nil, fmt.Errorf("unbalanced braces in elasticsearch index %q", indexTemplate)
+	}
+	endpoint := baseURL
+	if u, err := url.Parse(baseURL); err == nil && !strings.HasSuffix(u.Path, "/_bulk") {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/_bulk"
+		endpoint = u.String()
+	}
+	s, err := NewHTTPSink(endpoint, &esEncoder{index: indexTemplate})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create elasticsearch sink: %w", err)
+	}
+	return &ElasticsearchSink{HTTPSink: s}, nil
+}
+
+// SetAPIKey authenticates requests with an Elasticsearch API key.
+func (s *ElasticsearchSink) SetAPIKey(key string) {
+	s.SetHeader("Authorization", "ApiKey "+key)
+}
+
+// indexName expands the time layouts in the index template.
+func (enc *esEncoder) indexName(t time.Time) string {
+	tmpl := enc.index
+	if !strings.Contains(tmpl, "{") {
+		return tmpl
+	}
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(tmpl, '{')
+		end := strings.IndexByte(tmpl, '}')
+		if start < 0 || end < start {
+			sb.WriteString(tmpl)
+			return sb.String()
+		}
+		sb.WriteString(tmpl[:start])
+		sb.WriteString(t.UTC().Format(tmpl[start+1 : end]))
+		tmpl = tmpl[end+1:]
+	}
+}
+
+// Encode implements HTTPEncoder, producing bulk NDJSON with an index
+// action for every entry.
+func (enc *esEncoder) Encode(entries []*Entry) ([]byte, string, error) {
+	var buf bytes.Buffer
+	je := json.NewEncoder(&buf)
+	for _, e := range entries {
+		action := map[string]any{"index": map[string]string{"_index": enc.indexName(e.Time)}}
+		if err := je.Encode(action); err != nil {
+			return nil, "", err
+		}
+		doc := entryDocument(e, "message", "log.level", "process.pid")
+		doc["@timestamp"] = e.Time.UTC().Format(time.RFC3339Nano)
+		doc["host.name"] = e.Host
+		if err := je.Encode(doc); err != nil {
+			return nil, "", err
+		}
+	}
+	return buf.Bytes(), "application/x-ndjson", nil
+}
+
+type esBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// HandleResponse implements HTTPResponseHandler, inspecting the item
+// statuses of a bulk response.
+func (enc *esEncoder) HandleResponse(batch []*Entry, body []byte) ([]*Entry, error) {
+	var resp esBulkResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("invalid bulk response: %w", err)
+	}
+	if !resp.Errors {
+		return nil, nil
+	}
+
+	var (
+		retry    []*Entry
+		rejected int
+		reason   string
+	)
+	for i, item := range resp.Items {
+		if i >= len(batch) {
+			break
+		}
+		for _, result := range item {
+			switch {
+			case result.Status < 300:
+			case result.Status == http.StatusTooManyRequests || result.Status >= 500:
+				retry = append(retry, batch[i])
+			default:
+				rejected++
+				if reason == "" {
+					reason = fmt.Sprintf("%s: %s", result.Error.Type, result.Error.Reason)
+				}
+			}
+		}
+	}
+	if rejected > 0 {
+		return retry, fmt.Errorf("elasticsearch rejected %d entries (%s)", rejected, reason)
+	}
+	return retry, nil
+}
@@ -0,0 +1,146 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Helper bulk endpoint. Each call to respond decides the status of the
+// items of one request, in order.
+type testBulkServer struct {
+	sync.Mutex
+	*httptest.Server
+	requests [][]map[string]any
+	respond  func(call int, docs []map[string]any) []int
+	done     chan struct{}
+}
+
+func newTestBulkServer(t *testing.T, respond func(call int, docs []map[string]any) []int) *testBulkServer {
+	t.Helper()
+	srv := &testBulkServer{respond: respond, done: make(chan struct{}, 10)}
+	srv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+			t.Errorf("unexpected request %s %q", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		var docs []map[string]any
+		sc := bufio.NewScanner(r.Body)
+		for sc.Scan() {
+			var line map[string]any
+			if err := json.Unmarshal(sc.Bytes(), &line); err != nil {
+				t.Errorf("invalid NDJSON line %q", sc.Text())
+				continue
+			}
+			docs = append(docs, line)
+		}
+		srv.Lock()
+		call := len(srv.requests)
+		srv.requests = append(srv.requests, docs)
+		srv.Unlock()
+
+		statuses := srv.respond(call, docs)
+		resp := map[string]any{"errors": false}
+		var items []any
+		for _, st := range statuses {
+			item := map[string]any{"status": st}
+			if st >= 300 {
+				resp["errors"] = true
+				item["error"] = map[string]string{"type": "mapper_parsing_exception", "reason": "bad field"}
+			}
+			items = append(items, map[string]any{"index": item})
+		}
+		resp["items"] = items
+		json.NewEncoder(w).Encode(resp)
+		srv.done <- struct{}{}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (srv *testBulkServer) wait(t *testing.T, n int) [][]map[string]any {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-srv.done:
+		case <-time.After(3 * time.Second):
+			t.Fatalf("timed out waiting for bulk request %d", i+1)
+		}
+	}
+	srv.Lock()
+	defer srv.Unlock()
+	return srv.requests
+}
+
+func TestElasticsearchSink(t *testing.T) {
+	srv := newTestBulkServer(t, func(call int, docs []map[string]any) []int {
+		return []int{201}
+	})
+	s, err := NewElasticsearchSink(srv.URL, "logs-{2006.01.02}")
+	if err != nil {
+		t.Fatalf("Failed to create elasticsearch sink: %v", err)
+	}
+	s.SetAPIKey("abc")
+	s.SetBatchAge(time.Hour)
+
+	ts := time.Date(2024, 5, 1, 23, 30, 0, 0, time.FixedZone("X", -2*3600))
+	s.WriteEntry(&Entry{Time: ts, Level: LevelWarn, Message: "disk low", Host: "web1", PID: 7, Fields: Fields{"disk": "/var"}})
+	s.Close()
+
+	docs := srv.wait(t, 1)[0]
+	if len(docs) != 2 {
+		t.Fatalf("expected action and document, got %v", docs)
+	}
+	if idx := docs[0]["index"].(map[string]any)["_index"]; idx != "logs-2024.05.02" {
+		t.Errorf("expected index based on UTC date, got %v", idx)
+	}
+	doc := docs[1]
+	if doc["message"] != "disk low" || doc["log.level"] != "warn" || doc["host.name"] != "web1" ||
+		doc["process.pid"] != float64(7) || doc["disk"] != "/var" || doc["@timestamp"] != "2024-05-02T01:30:00Z" {
+		t.Errorf("unexpected document: %v", doc)
+	}
+}
+
+func TestElasticsearchSink_PartialFailure(t *testing.T) {
+	srv := newTestBulkServer(t, func(call int, docs []map[string]any) []int {
+		if call == 0 {
+			// ok, rejected for good, rejected temporarily
+			return []int{201, 400, 429}
+		}
+		return []int{201}
+	})
+	s, err := NewElasticsearchSink(srv.URL+"/", "logs")
+	if err != nil {
+		t.Fatalf("Failed to create elasticsearch sink: %v", err)
+	}
+	s.SetBatchAge(time.Hour)
+	defer s.Close()
+
+	for _, msg := range []string{"ok", "bad", "busy"} {
+		s.WriteEntry(&Entry{Time: time.Now(), Message: msg})
+	}
+	err = s.Flush()
+	if err == nil {
+		t.Fatal("expected error for the rejected entry")
+	}
+
+	requests := srv.wait(t, 2)
+	if len(requests) != 2 || len(requests[1]) != 2 || requests[1][1]["message"] != "busy" {
+		t.Fatalf("expected only the throttled entry to be resent, got %v", requests)
+	}
+}
+
+func TestNewElasticsearchSink_InvalidArgs(t *testing.T) {
+	if _, err := NewElasticsearchSink("http://localhost:9200", ""); err == nil {
+		t.Error("Expected error for empty index")
+	}
+	if _, err := NewElasticsearchSink("http://localhost:9200", "logs-{2006"); err == nil {
+		t.Error("Expected error for unbalanced braces")
+	}
+	if _, err := NewElasticsearchSink("localhost:9200", "logs"); err == nil {
+		t.Error("Expected error for invalid URL")
+	}
+}
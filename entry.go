@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level identifies the severity of a log entry.
+type Level int
+
+const (
+	LevelTrace Level = iota
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelFatal
+)
+
+// String returns the lower case name of the level.
+func (lvl Level) String() string {
+	switch lvl {
+	case LevelTrace:
+		return "trace"
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	case LevelFatal:
+		return "fatal"
+	}
+	return "unknown"
+}
+
+// ParseLevel returns the level with the given name, as returned by
+// Level.String.
+func ParseLevel(name string) (Level, error) {
+	for lvl := LevelTrace; lvl <= LevelFatal; lvl++ {
+		if strings.EqualFold(name, lvl.String()) {
+			return lvl, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q", name)
+}
+
+// Fields holds structured data attached to log entries.
+type Fields map[string]any
+
+// Entry is a single log statement as handed to sinks.
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	Fields  Fields
+	Host    string
+	PID     int
+}
+
+// Sink receives structured log entries. Sinks are registered on a Logger
+// and are written to in addition to, or instead of, its text output.
+type Sink interface {
+	WriteEntry(e *Entry) error
+	Close() error
+}
+
+var (
+	hostnameOnce sync.Once
+	hostname     string
+)
+
+// localHostname returns the host name reported in entries.
+func localHostname() string {
+	hostnameOnce.Do(func() {
+		hostname, _ = os.Hostname()
+	})
+	return hostname
+}
@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Sources of errors reported to the ErrorHandler.
+const (
+	ErrorSourceOutput  = "output"
+	ErrorSourceFile    = "file"
+	ErrorSourceSyslog  = "syslog"
+	ErrorSourceJournal = "journald"
+	ErrorSourceSink    = "sink"
+	ErrorSourceHook    = "hook"
+)
+
+const defaultErrorInterval = 10 * time.Second
+
+// ErrorHandler is called with failures inside the logging subsystem,
+// such as write errors, instead of logging them, which could fail again
+// or loop. It may be called concurrently.
+type ErrorHandler func(source string, err error)
+
+var (
+	errMu      sync.Mutex
+	errHandler = NewRateLimitedErrorHandler(os.Stderr, defaultErrorInterval)
+	errCounts  = make(map[string]uint64)
+)
+
+// SetErrorHandler sets the handler for errors of all loggers and sinks
+// that have no handler of their own, nil restores the default, which
+// writes at most one error per source every 10 seconds to stderr.
+func SetErrorHandler(h ErrorHandler) {
+	if h == nil {
+		h = NewRateLimitedErrorHandler(os.Stderr, defaultErrorInterval)
+	}
+	errMu.Lock()
+	defer errMu.Unlock()
+	errHandler = h
+}
+
+// ErrorCounts returns the number of errors reported so far, by source.
+func ErrorCounts() map[string]uint64 {
+	errMu.Lock()
+	defer errMu.Unlock()
+	counts := make(map[string]uint64, len(errCounts))
+	for k, v := range errCounts {
+		counts[k] = v
+	}
+	return counts
+}
+
+// NewRateLimitedErrorHandler returns a handler writing errors to w, at
+// most one per source and interval. The number of errors dropped in
+// between is added to the next message.
+func NewRateLimitedErrorHandler(w io.Writer, interval time.Duration) ErrorHandler {
+	var (
+		mu         sync.Mutex
+		last       = make(map[string]time.Time)
+		suppressed = make(map[string]int)
+	)
+	return func(source string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		now := time.Now()
+		if t, ok := last[source]; ok && now.Sub(t) < interval {
+			suppressed[source]++
+			return
+		}
+		last[source] = now
+		if n := suppressed[source]; n > 0 {
+			fmt.Fprintf(w, "logger: %s: %v (%d similar errors suppressed)\n", source, err, n)
+			delete(suppressed, source)
+			return
+		}
+		fmt.Fprintf(w, "logger: %s: %v\n", source, err)
+	}
+}
+
+// reportError counts an error and passes it to h, or to the global
+// handler when h is nil.
+func reportError(h ErrorHandler, source string, err error) {
+	errMu.Lock()
+	errCounts[source]++
+	if h == nil {
+		h = errHandler
+	}
+	errMu.Unlock()
+	h(source, err)
+}
+
+// SetErrorHandler sets the handler for errors of this logger, its file
+// output and hooks, instead of the global handler.
+func (l *Logger) SetErrorHandler(h ErrorHandler) {
+	l.Lock()
+	defer l.Unlock()
+	l.onError = h
+}
+
+func (l *Logger) reportError(source string, err error) {
+	l.Lock()
+	h := l.onError
+	l.Unlock()
+	reportError(h, source, err)
+}
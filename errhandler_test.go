@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Helper file that fails every write.
+type failingFile struct {
+	name string
+}
+
+func (f *failingFile) Write(b []byte) (int, error) { return 0, errors.New("disk full") }
+func (f *failingFile) Close() error                { return nil }
+func (f *failingFile) Name() string                { return f.name }
+
+// Helper sink that fails every write.
+type failingSink struct{}
+
+func (failingSink) WriteEntry(*Entry) error { return errors.New("sink down") }
+func (failingSink) Close() error            { return nil }
+
+// Helper error handler collecting reported errors.
+type errorCollector struct {
+	sync.Mutex
+	errs []string
+}
+
+func (c *errorCollector) handle(source string, err error) {
+	c.Lock()
+	defer c.Unlock()
+	c.errs = append(c.errs, source+": "+err.Error())
+}
+
+func (c *errorCollector) Errors() []string {
+	c.Lock()
+	defer c.Unlock()
+	return append([]string(nil), c.errs...)
+}
+
+func TestRateLimitedErrorHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewRateLimitedErrorHandler(&buf, 30*time.Millisecond)
+	for i := 0; i < 3; i++ {
+		h(ErrorSourceFile, errors.New("disk full"))
+	}
+	h(ErrorSourceSink, errors.New("sink down"))
+	time.Sleep(40 * time.Millisecond)
+	h(ErrorSourceFile, errors.New("disk still full"))
+
+	want := "logger: file: disk full\n" +
+		"logger: sink: sink down\n" +
+		"logger: file: disk still full (2 similar errors suppressed)\n"
+	if buf.String() != want {
+		t.Errorf("Unexpected handler output:\n%s", buf.String())
+	}
+}
+
+func TestLoggerErrorHandler_FileWriteFailure(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.log")
+	l := NewFileLogger(file, false, false, false, false)
+	defer l.Close()
+	var c errorCollector
+	l.SetErrorHandler(c.handle)
+	before := ErrorCounts()[ErrorSourceFile]
+
+	l.fl.Lock()
+	l.fl.file = &failingFile{name: file}
+	l.fl.Unlock()
+	l.fl.logDirect(l.infoLabel, "direct")
+	l.Noticef("through the logger")
+
+	errs := c.Errors()
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 reported errors, got %v", errs)
+	}
+	for _, e := range errs {
+		if !strings.HasPrefix(e, "file: ") || !strings.Contains(e, "disk full") {
+			t.Errorf("Unexpected reported error %q", e)
+		}
+	}
+	if n := ErrorCounts()[ErrorSourceFile] - before; n != 2 {
+		t.Errorf("Expected file error count to grow by 2, got %d", n)
+	}
+}
+
+func TestSetErrorHandler(t *testing.T) {
+	var c errorCollector
+	SetErrorHandler(c.handle)
+	defer SetErrorHandler(nil)
+
+	l := NewSinkLogger(false, false, failingSink{})
+	l.Noticef("lost")
+	if errs := c.Errors(); len(errs) != 1 || !strings.HasPrefix(errs[0], "sink: ") ||
+		!strings.Contains(errs[0], "sink down") {
+		t.Errorf("Expected sink error to reach the global handler, got %v", errs)
+	}
+}
@@ -2,6 +2,7 @@ package logger
 
 import (
     "fmt"
+    "io"
     "os"
     "path/filepath"
     "strings"
@@ -23,20 +24,64 @@ type writerAndCloser interface {
 type FileLogger struct {
     currentSize       int64
     isRotationAllowed int32
-    sync.Mutex
+    sync.RWMutex
     logger                *Logger
     file                  writerAndCloser
     rotationLimit         int64
     originalRotationLimit int64
     processIDPrefix       string
-    includeTimestamp      bool
     isClosed              bool
     maxBackupFiles        int
+    fallback              *fileFallbackState
+    callbacks             *fileCallbacks
+    fileMode              os.FileMode
+    explicitMode          bool
+    uid, gid              int
+    dirMode               os.FileMode
+    syncer                *fileSync
+    buffer                *fileBuffer
+    format                textFormat
+    clock                 Clock
 }
 
-func newFileLogger(filename, processIDPrefix string, includeTimestamp bool) (*FileLogger, error) {
-    fileflags := os.O_WRONLY | os.O_APPEND | os.O_CREATE
-    file, err := os.OpenFile(filename, fileflags, defaultLogPerms)
+func newFileLogger(filename, processIDPrefix string, includeTimestamp bool, opts ...LogOption) (*FileLogger, error) {
+    fl := &FileLogger{
+        isRotationAllowed: 0,
+        processIDPrefix:   processIDPrefix,
+        fileMode:          defaultLogPerms,
+        uid:               -1,
+        gid:               -1,
+        clock:             clockOption(opts...),
+    }
+    var syncPolicy SyncPolicy
+    var bufferPolicy BufferPolicy
+    for _, opt := range opts {
+        switch v := opt.(type) {
+        case LogFileMode:
+            fl.fileMode, fl.explicitMode = os.FileMode(v), true
+        case LogFileOwner:
+            fl.uid, fl.gid = v.UID, v.GID
+        case LogDirMode:
+            fl.dirMode = os.FileMode(v)
+        case SyncPolicy:
+            if err := v.validate(); err != nil {
+                return nil, err
+            }
+            syncPolicy = v
+        case BufferPolicy:
+            if err := v.validate(); err != nil {
+                return nil, err
+            }
+            bufferPolicy = v
+        case LogLineEnding:
+            if err := v.validate(); err != nil {
+                return nil, err
+            }
+        }
+    }
+    fl.format = newTextFormat(includeTimestamp, opts...)
+
+    file, err := fl.openLogFile(filename)
     if err != nil {
         return nil, fmt.Errorf("unable to open log file %q: %w", filename, err)
     }
@@ -47,22 +92,50 @@ func newFileLogger(filename, processIDPrefix string, includeTimestamp bool) (*Fi
         return nil, fmt.Errorf("unable to get file stats for %q: %w", filename, err)
     }
 
-    fl := &FileLogger{
-        isRotationAllowed: 0,
-        file:              file,
-        currentSize:       stats.Size(),
-        processIDPrefix:   processIDPrefix,
-        includeTimestamp:  includeTimestamp,
-    }
+    fl.file = file
+    fl.currentSize = stats.Size()
+    fl.setSyncPolicy(syncPolicy)
+    fl.setBufferPolicy(bufferPolicy)
     return fl, nil
 }
 
+// openLogFile opens or creates a log file, creating its directory and
+// applying the configured permissions and owner as needed.
+func (fl *FileLogger) openLogFile(name string) (*os.File, error) {
+    if fl.dirMode != 0 {
+        if err := os.MkdirAll(filepath.Dir(name), fl.dirMode); err != nil {
+            return nil, fmt.Errorf("unable to create log directory: %w", err)
+        }
+    }
+    fileflags := os.O_WRONLY | os.O_APPEND | os.O_CREATE
+    file, err := os.OpenFile(name, fileflags, fl.fileMode)
+    if err != nil {
+        return nil, err
+    }
+    // The mode passed to OpenFile is reduced by the umask and does not
+    // apply to existing files.
+    if fl.explicitMode {
+        if err := file.Chmod(fl.fileMode); err != nil {
+            file.Close()
+            return nil, fmt.Errorf("unable to change log file mode: %w", err)
+        }
+    }
+    if fl.uid != -1 || fl.gid != -1 {
+        if err := file.Chown(fl.uid, fl.gid); err != nil {
+            file.Close()
+            return nil, fmt.Errorf("unable to change log file owner: %w", err)
+        }
+    }
+    return file, nil
+}
+
 func (fl *FileLogger) setLimit(limit int64) {
     fl.Lock()
-    defer fl.Unlock()
     fl.originalRotationLimit, fl.rotationLimit = limit, limit
     atomic.StoreInt32(&fl.isRotationAllowed, 1)
-    rotateNow := fl.currentSize > fl.rotationLimit
+    rotateNow := fl.currentSize+int64(fl.buffered()) > fl.rotationLimit
+    // Log without holding the lock, the write triggers the rotation.
+    fl.Unlock()
     if rotateNow {
         fl.logger.Noticef("Rotating logfile...")
     }
@@ -74,26 +147,33 @@ func (fl *FileLogger) setMaxNumFiles(max int) {
     fl.maxBackupFiles = max
 }
 
-func (fl *FileLogger) logDirect(label, format string, v ...any) int {
+// formatDirect formats lines like the log.Logger writing to the file.
+func (fl *FileLogger) formatDirect(label, format string, v ...any) []byte {
+    var header []byte
+    if fl.processIDPrefix != "" {
+        header = append(header, fl.processIDPrefix...)
+    }
+    header = fl.format.appendTimestamp(header, fl.now())
+    header = append(header, label...)
+
     var logBuffer = [256]byte{}
     var logEntry = logBuffer[:0]
-    if fl.processIDPrefix != "" {
-        logEntry = append(logEntry, fl.processIDPrefix...)
-    }
-    if fl.includeTimestamp {
-        now := time.Now()
-        year, month, day := now.Date()
-        hour, min, sec := now.Clock()
-        microsec := now.Nanosecond() / 1000
-        logEntry = append(logEntry, fmt.Sprintf("%04d/%02d/%02d %02d:%02d:%02d.%06d ",
-            year, month, day, hour, min, sec, microsec)...)
-    }
-    logEntry = append(logEntry, label...)
-    logEntry = append(logEntry, fmt.Sprintf(format, v...)...)
-    logEntry = append(logEntry, '\r', '\n')
-    _, err := fl.file.Write(logEntry)
+    for _, line := range fl.format.lines(fmt.Sprintf(format, v...)) {
+        logEntry = append(logEntry, header...)
+        logEntry = append(logEntry, line...)
+        logEntry = append(logEntry, fl.format.eol...)
+        logEntry = append(logEntry, '\n')
+    }
+    return logEntry
+}
+
+func (fl *FileLogger) logDirect(label, format string, v ...any) int {
+    logEntry := fl.formatDirect(label, format, v...)
+    n, err := fl.file.Write(logEntry)
+    countBytes(n)
     if err != nil {
-        fl.logger.Noticef("Error writing to log file: %v", err)
+        countWriteError(ErrorSourceFile)
+        fl.logger.reportError(ErrorSourceFile, fmt.Errorf("error writing to log file: %w", err))
     }
     return len(logEntry)
 }
@@ -104,6 +184,7 @@ func (fl *FileLogger) logPurge(fname string) {
     logBase := filepath.Base(fname)
     entries, err := os.ReadDir(logDir)
     if err != nil {
+        metrics.purgeFailures.Add(1)
         fl.logDirect(fl.logger.errorLabel, "Unable to read directory %q for log purge (%v), will attempt next rotation", logDir, err)
         return
     }
@@ -121,72 +202,185 @@ func (fl *FileLogger) logPurge(fname string) {
 
     currBackups := len(backups)
     maxBackups := fl.maxBackupFiles - 1
+    var removed []string
     if currBackups > maxBackups {
         // backups sorted oldest to latest based on timestamped lexical filename (ReadDir)
         for i := 0; i < currBackups-maxBackups; i++ {
             if err := os.Remove(filepath.Join(logDir, string(os.PathSeparator), backups[i])); err != nil {
+                metrics.purgeFailures.Add(1)
                 fl.logDirect(fl.logger.errorLabel, "Unable to remove backup log file %q (%v), will attempt next rotation", backups[i], err)
                 // Bail fast, we'll try again next rotation
-                return
+                break
             }
+            metrics.purges.Add(1)
+            removed = append(removed, filepath.Join(logDir, backups[i]))
             fl.logDirect(fl.logger.infoLabel, "Purged log file %q", backups[i])
         }
     }
+    if len(removed) > 0 && fl.callbacks != nil {
+        fl.callbacks.purged(removed)
+    }
 }
 
 func (fl *FileLogger) Write(b []byte) (int, error) {
-    if atomic.LoadInt32(&fl.isRotationAllowed) == 0 {
+    // Without rotation, buffering, syncing or a fallback, only the size
+    // is updated and writes can run concurrently. The read lock keeps a
+    // reopen from replacing the file in the middle of a write.
+    fl.RLock()
+    if atomic.LoadInt32(&fl.isRotationAllowed) == 0 && fl.buffer == nil && fl.syncer == nil && fl.fallback == nil {
         n, err := fl.file.Write(b)
+        atomic.AddInt64(&fl.currentSize, int64(n))
+        fl.RUnlock()
+        countBytes(n)
         if err != nil {
+            countWriteError(ErrorSourceFile)
             return n, fmt.Errorf("error writing to log file: %w", err)
         }
-        atomic.AddInt64(&fl.currentSize, int64(n))
-        return n, err
+        return n, nil
     }
+    fl.RUnlock()
 
     fl.Lock()
     defer fl.Unlock()
-    n, err := fl.file.Write(b)
+    n, err := fl.writeBuffered(b)
     if err != nil {
-        return n, fmt.Errorf("error writing to log file during rotation: %w", err)
+        return n, fmt.Errorf("error writing to log file: %w", err)
     }
+    err = fl.syncWritten(n)
 
-    fl.currentSize += int64(n)
-    if fl.currentSize > fl.rotationLimit {
-        if err := fl.file.Close(); err != nil {
-            fl.rotationLimit *= 2
-            fl.logDirect(fl.logger.errorLabel, "Unable to close logfile for rotation (%v), will attempt next rotation at size %v", err, fl.rotationLimit)
+    if atomic.LoadInt32(&fl.isRotationAllowed) == 1 && fl.currentSize+int64(fl.buffered()) > fl.rotationLimit {
+        if _, err := fl.rotate(); err != nil {
             return n, err
         }
+    }
 
-        fname := fl.file.Name()
-        now := time.Now()
-        bak := fmt.Sprintf("%s.%04d.%02d.%02d.%02d.%02d.%02d.%09d", fname,
-            now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(),
-            now.Second(), now.Nanosecond())
-        if err := os.Rename(fname, bak); err != nil {
-            return n, fmt.Errorf("error renaming log file during rotation: %w", err)
+    return n, err
+}
+
+// rotate renames the log file to a timestamped backup, reopens it and
+// purges old backups. Lock must be held.
+func (fl *FileLogger) rotate() (string, error) {
+    // Complete the file being rotated, so that the backup and its size
+    // include all entries written so far.
+    if err := fl.flushBuffer(); err != nil {
+        fl.logger.reportError(ErrorSourceFile, err)
+    }
+    if fl.syncer != nil {
+        if err := fl.sync(); err != nil {
+            fl.logger.reportError(ErrorSourceFile, err)
         }
+    }
+    if err := fl.file.Close(); err != nil {
+        fl.rotationLimit *= 2
+        fl.logDirect(fl.logger.errorLabel, "Unable to close logfile for rotation (%v), will attempt next rotation at size %v", err, fl.rotationLimit)
+        return "", err
+    }
 
-        fileflags := os.O_WRONLY | os.O_APPEND | os.O_CREATE
-        file, err := os.OpenFile(fname, fileflags, defaultLogPerms)
-        if err != nil {
-            return n, fmt.Errorf("unable to re-open the logfile %q after rotation: %w", fname, err)
+    fname := fl.file.Name()
+    now := fl.now()
+    bak := fmt.Sprintf("%s.%04d.%02d.%02d.%02d.%02d.%02d.%09d", fname,
+        now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(),
+        now.Second(), now.Nanosecond())
+    if err := os.Rename(fname, bak); err != nil {
+        fl.restoreFile(fname)
+        return "", fmt.Errorf("error renaming log file during rotation: %w", err)
+    }
+
+    file, err := fl.openLogFile(fname)
+    if err != nil {
+        // Put the original file back, so that writes go on to it.
+        if os.Rename(bak, fname) == nil {
+            fl.restoreFile(fname)
         }
+        return "", fmt.Errorf("unable to re-open the logfile %q after rotation: %w", fname, err)
+    }
 
-        fl.file = file
-        n = fl.logDirect(fl.logger.infoLabel, "Rotated log, backup saved as %q", bak)
-        fl.currentSize = int64(n)
-        fl.rotationLimit = fl.originalRotationLimit
-        if fl.maxBackupFiles > 0 {
-            fl.logPurge(fname)
+    fl.file = file
+    if fl.syncer != nil {
+        if err := syncDir(filepath.Dir(fname)); err != nil {
+            fl.logger.reportError(ErrorSourceFile, err)
         }
     }
+    metrics.rotations.Add(1)
+    bakSize := fl.currentSize
+    n := fl.logDirect(fl.logger.infoLabel, "Rotated log, backup saved as %q", bak)
+    fl.currentSize = int64(n)
+    fl.rotationLimit = fl.originalRotationLimit
+    if fl.callbacks != nil {
+        fl.callbacks.rotated(RotateEvent{Backup: bak, BackupSize: bakSize, Path: fname, Size: fl.currentSize})
+    }
+    if fl.maxBackupFiles > 0 {
+        fl.logPurge(fname)
+    }
+    return bak, nil
+}
 
-    return n, err
+// restoreFile reopens the log file after a failed rotation, so that
+// writes do not go to the closed handle. Lock must be held.
+func (fl *FileLogger) restoreFile(fname string) {
+    file, err := fl.openLogFile(fname)
+    if err != nil {
+        fl.logger.reportError(ErrorSourceFile, fmt.Errorf("unable to re-open the logfile %q after failed rotation: %w", fname, err))
+        return
+    }
+    fl.file = file
+    if stats, err := file.Stat(); err == nil {
+        fl.currentSize = stats.Size()
+    }
+}
+
+// forceRotate rotates the log file regardless of its size.
+func (fl *FileLogger) forceRotate() (string, error) {
+    fl.Lock()
+    defer fl.Unlock()
+    if fl.isClosed {
+        return "", fmt.Errorf("log file is closed")
+    }
+    return fl.rotate()
+}
+
+// reopen closes and reopens the log file by name, e.g. after it was
+// moved by an external tool.
+func (fl *FileLogger) reopen() error {
+    fl.Lock()
+    defer fl.Unlock()
+    if fl.isClosed {
+        return fmt.Errorf("log file is closed")
+    }
+    fname := fl.file.Name()
+    if err := fl.flushBuffer(); err != nil {
+        fl.logger.reportError(ErrorSourceFile, err)
+    }
+    // Go on after a failed close, reopening recovers a broken handle.
+    if err := fl.file.Close(); err != nil {
+        fl.logger.reportError(ErrorSourceFile, fmt.Errorf("error closing log file: %w", err))
+    }
+    file, err := fl.openLogFile(fname)
+    if err != nil {
+        return fmt.Errorf("unable to re-open the logfile %q: %w", fname, err)
+    }
+    stats, err := file.Stat()
+    if err != nil {
+        file.Close()
+        return fmt.Errorf("unable to get file stats for %q: %w", fname, err)
+    }
+    fl.file = file
+    fl.currentSize = stats.Size()
+    return nil
 }
 
 func (fl *FileLogger) close() error {
+    fl.Lock()
+    s, b := fl.syncer, fl.buffer
+    fl.Unlock()
+    // Stop without the lock, the goroutines may be waiting for it.
+    if s != nil {
+        s.ticker.stop()
+    }
+    if b != nil {
+        b.ticker.stop()
+    }
+
     fl.Lock()
     defer fl.Unlock()
 
@@ -195,6 +389,22 @@ func (fl *FileLogger) close() error {
     }
 
     fl.isClosed = true
+    if err := fl.flushBuffer(); err != nil {
+        fl.logger.reportError(ErrorSourceFile, err)
+    }
+    // Later writes go to the closed file and fail, rather than being
+    // buffered and never written.
+    fl.buffer = nil
+    if fl.syncer != nil {
+        if err := fl.sync(); err != nil {
+            fl.logger.reportError(ErrorSourceFile, err)
+        }
+    }
+    if fl.fallback != nil {
+        if c, ok := fl.fallback.fb.(io.Closer); ok {
+            c.Close()
+        }
+    }
     if err := fl.file.Close(); err != nil {
         return fmt.Errorf("error closing log file: %w", err)
     }
@@ -0,0 +1,148 @@
+package logger
+
+import (
+    "fmt"
+    "time"
+)
+
+// BufferPolicy buffers file logger output in memory, so that entries do
+// not each result in a write to the log file. The buffer is written
+// when it holds Size bytes, every FlushInterval if set, before rotations
+// and syncs, on Flush and Close, and right after Error and Fatal
+// entries. It can be passed to NewFileLogger as an option or set with
+// SetBufferPolicy. A zero Size disables buffering. Buffering has no
+// effect together with SyncEveryWrite, which writes the buffer on every
+// entry.
+type BufferPolicy struct {
+    Size          int
+    FlushInterval time.Duration
+}
+
+func (p BufferPolicy) isLoggerOption() {}
+
+func (p BufferPolicy) validate() error {
+    if p.Size < 0 || p.FlushInterval < 0 {
+        return fmt.Errorf("invalid buffer policy: %+v", p)
+    }
+    return nil
+}
+
+// fileBuffer holds output of a FileLogger not yet written to the file.
+type fileBuffer struct {
+    policy BufferPolicy
+    data   []byte
+    ticker *fileTicker
+}
+
+// SetBufferPolicy sets the buffering of the log file output. Output
+// buffered under the previous policy is written first.
+func (l *Logger) SetBufferPolicy(p BufferPolicy) error {
+    if err := p.validate(); err != nil {
+        return err
+    }
+    l.Lock()
+    if l.fl == nil {
+        l.Unlock()
+        return fmt.Errorf("can set buffer policy only for file logger")
+    }
+    fl := l.fl
+    l.Unlock()
+    return fl.setBufferPolicy(p)
+}
+
+// Flush writes buffered log file output and flushes sinks that buffer
+// entries.
+func (l *Logger) Flush() error {
+    l.Lock()
+    fl := l.fl
+    l.Unlock()
+    var err error
+    if fl != nil {
+        fl.Lock()
+        err = fl.flushBuffer()
+        fl.Unlock()
+    }
+    l.flushSinks()
+    return err
+}
+
+func (fl *FileLogger) setBufferPolicy(p BufferPolicy) error {
+    fl.Lock()
+    err := fl.flushBuffer()
+    old := fl.buffer
+    fl.buffer = nil
+    if p.Size > 0 && !fl.isClosed {
+        b := &fileBuffer{policy: p, data: make([]byte, 0, p.Size)}
+        if p.FlushInterval > 0 {
+            b.ticker = startFileTicker(p.FlushInterval, fl.flushTick)
+        }
+        fl.buffer = b
+    }
+    fl.Unlock()
+    if old != nil {
+        old.ticker.stop()
+    }
+    return err
+}
+
+// flushTick writes the buffered output on the flush interval.
+func (fl *FileLogger) flushTick() {
+    fl.Lock()
+    defer fl.Unlock()
+    if err := fl.flushBuffer(); err != nil && fl.logger != nil {
+        fl.logger.reportError(ErrorSourceFile, err)
+    }
+}
+
+// writeBuffered adds b to the buffer, writing the buffer to the file
+// when it is full, or writes b directly without buffer. Lock must be
+// held.
+func (fl *FileLogger) writeBuffered(b []byte) (int, error) {
+    buf := fl.buffer
+    if buf == nil {
+        return fl.writeFile(b)
+    }
+    buf.data = append(buf.data, b...)
+    if len(buf.data) < buf.policy.Size {
+        return len(b), nil
+    }
+    _, err := fl.writeFile(buf.data)
+    buf.data = buf.data[:0]
+    return len(b), err
+}
+
+// flushBuffer writes the buffered output to the file. Lock must be held.
+func (fl *FileLogger) flushBuffer() error {
+    buf := fl.buffer
+    if buf == nil || len(buf.data) == 0 {
+        return nil
+    }
+    _, err := fl.writeFile(buf.data)
+    buf.data = buf.data[:0]
+    if err != nil {
+        return fmt.Errorf("error writing to log file: %w", err)
+    }
+    return nil
+}
+
+// buffered returns the number of bytes waiting in the buffer. Lock must
+// be held.
+func (fl *FileLogger) buffered() int {
+    if fl.buffer == nil {
+        return 0
+    }
+    return len(fl.buffer.data)
+}
+
+// flushAfter writes buffered output right away after entries of severe
+// levels, so that they are not lost if the process dies.
+func (fl *FileLogger) flushAfter(level Level) {
+    if level < LevelError {
+        return
+    }
+    fl.Lock()
+    defer fl.Unlock()
+    if err := fl.flushBuffer(); err != nil && fl.logger != nil {
+        fl.logger.reportError(ErrorSourceFile, err)
+    }
+}
@@ -0,0 +1,125 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readLog(t *testing.T, file string) string {
+	t.Helper()
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	return string(content)
+}
+
+func TestFileLoggerBuffer_Size(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.log")
+	l := NewFileLogger(file, false, false, false, false, BufferPolicy{Size: 64})
+	defer l.Close()
+
+	l.Noticef("short")
+	if content := readLog(t, file); content != "" {
+		t.Fatalf("Expected the entry to be buffered, got %q", content)
+	}
+	l.Noticef("%s", strings.Repeat("x", 64))
+	if content := readLog(t, file); !strings.Contains(content, "short") || !strings.Contains(content, "xxx") {
+		t.Errorf("Expected a full buffer to be written, got %q", content)
+	}
+}
+
+func TestFileLoggerBuffer_FlushAndClose(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.log")
+	l := NewFileLogger(file, false, false, false, false, BufferPolicy{Size: 4096})
+
+	l.Noticef("flushed")
+	if err := l.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	if content := readLog(t, file); !strings.Contains(content, "flushed") {
+		t.Errorf("Expected Flush to write the buffer, got %q", content)
+	}
+
+	l.Noticef("closed")
+	l.Close()
+	if content := readLog(t, file); !strings.Contains(content, "closed") {
+		t.Errorf("Expected Close to write the buffer, got %q", content)
+	}
+
+	// Writes after Close fail instead of disappearing into the buffer.
+	if _, err := l.fl.Write([]byte("after close\n")); err == nil {
+		t.Error("Expected an error writing after Close")
+	}
+}
+
+func TestFileLoggerBuffer_ErrorFlushes(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.log")
+	l := NewFileLogger(file, false, false, false, false, BufferPolicy{Size: 4096})
+	defer l.Close()
+
+	l.Noticef("before")
+	l.Errorf("failure")
+	if content := readLog(t, file); !strings.Contains(content, "before") || !strings.Contains(content, "failure") {
+		t.Errorf("Expected an error entry to flush the buffer, got %q", content)
+	}
+}
+
+func TestFileLoggerBuffer_Interval(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.log")
+	l := NewFileLogger(file, false, false, false, false)
+	defer l.Close()
+	if err := l.SetBufferPolicy(BufferPolicy{Size: 4096, FlushInterval: 10 * time.Millisecond}); err != nil {
+		t.Fatalf("Failed to set buffer policy: %v", err)
+	}
+
+	l.Noticef("periodic")
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(readLog(t, file), "periodic") {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the buffer to be flushed on the interval")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := l.SetBufferPolicy(BufferPolicy{Size: -1}); err == nil {
+		t.Error("Expected error for a negative buffer size")
+	}
+	if err := newTestStdLogger(false, false, false, false, false).SetBufferPolicy(BufferPolicy{Size: 1}); err == nil {
+		t.Error("Expected error setting a buffer policy without log file")
+	}
+}
+
+func TestFileLoggerBuffer_Rotate(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.log")
+	l := NewFileLogger(file, false, false, false, false, BufferPolicy{Size: 4096})
+	defer l.Close()
+	l.SetSizeLimit(100)
+
+	var events []RotateEvent
+	done := make(chan struct{}, 1)
+	l.OnRotate(func(ev RotateEvent) {
+		events = append(events, ev)
+		done <- struct{}{}
+	})
+
+	for i := 0; i < 5; i++ {
+		l.Noticef("buffered entry %d", i)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the buffered size to trigger a rotation")
+	}
+
+	content := readLog(t, events[0].Backup)
+	if !strings.Contains(content, "buffered entry 0") || !strings.Contains(content, "buffered entry 4") {
+		t.Errorf("Expected the buffer to be written before rotation, got %q", content)
+	}
+	if events[0].BackupSize != int64(len(content)) {
+		t.Errorf("Expected backup size %d, got %d", len(content), events[0].BackupSize)
+	}
+}
@@ -0,0 +1,152 @@
+package logger
+
+import (
+    "fmt"
+    "sync"
+)
+
+// RotateEvent describes a completed log file rotation.
+type RotateEvent struct {
+    Backup     string // path the log file was renamed to
+    BackupSize int64
+    Path       string // path of the new log file
+    Size       int64  // size of the new log file after the rotation notice
+}
+
+// fileCallbacks runs rotation and purge callbacks in order on their own
+// goroutine, so that they never run with the FileLogger lock held.
+type fileCallbacks struct {
+    sync.Mutex
+    onRotate func(RotateEvent)
+    onPurge  func(removed []string)
+    queue    []func()
+    wake     chan struct{}
+    done     chan struct{}
+    closed   bool
+}
+
+// OnRotate sets a function called after every rotation of the log file,
+// e.g. to upload the backup. It runs asynchronously; calls are made one
+// at a time, in order.
+func (l *Logger) OnRotate(fn func(RotateEvent)) error {
+    cb, err := l.fileCallbacks()
+    if err != nil {
+        return err
+    }
+    cb.Lock()
+    defer cb.Unlock()
+    cb.onRotate = fn
+    return nil
+}
+
+// OnPurge sets a function called with the paths of the backups removed
+// after a rotation. It runs asynchronously like OnRotate callbacks.
+func (l *Logger) OnPurge(fn func(removed []string)) error {
+    cb, err := l.fileCallbacks()
+    if err != nil {
+        return err
+    }
+    cb.Lock()
+    defer cb.Unlock()
+    cb.onPurge = fn
+    return nil
+}
+
+// fileCallbacks returns the callbacks of the file logger, starting their
+// goroutine on first use.
+func (l *Logger) fileCallbacks() (*fileCallbacks, error) {
+    l.Lock()
+    if l.fl == nil {
+        l.Unlock()
+        return nil, fmt.Errorf("can set rotation callbacks only for file logger")
+    }
+    fl := l.fl
+    l.Unlock()
+
+    fl.Lock()
+    defer fl.Unlock()
+    if fl.callbacks == nil {
+        fl.callbacks = &fileCallbacks{
+            wake: make(chan struct{}, 1),
+            done: make(chan struct{}),
+        }
+        go fl.callbacks.run(l)
+    }
+    return fl.callbacks, nil
+}
+
+// rotated queues the OnRotate callback.
+func (cb *fileCallbacks) rotated(ev RotateEvent) {
+    cb.Lock()
+    fn := cb.onRotate
+    cb.Unlock()
+    if fn != nil {
+        cb.push(func() { fn(ev) })
+    }
+}
+
+// purged queues the OnPurge callback.
+func (cb *fileCallbacks) purged(removed []string) {
+    cb.Lock()
+    fn := cb.onPurge
+    cb.Unlock()
+    if fn != nil {
+        cb.push(func() { fn(removed) })
+    }
+}
+
+func (cb *fileCallbacks) push(fn func()) {
+    cb.Lock()
+    defer cb.Unlock()
+    if cb.closed {
+        return
+    }
+    cb.queue = append(cb.queue, fn)
+    select {
+    case cb.wake <- struct{}{}:
+    default:
+    }
+}
+
+func (cb *fileCallbacks) run(l *Logger) {
+    defer close(cb.done)
+    for range cb.wake {
+        cb.drain(l)
+    }
+    cb.drain(l)
+}
+
+// drain runs the queued callbacks, reporting panics as errors.
+func (cb *fileCallbacks) drain(l *Logger) {
+    for {
+        cb.Lock()
+        if len(cb.queue) == 0 {
+            cb.Unlock()
+            return
+        }
+        fn := cb.queue[0]
+        cb.queue = cb.queue[1:]
+        cb.Unlock()
+        func() {
+            defer func() {
+                if r := recover(); r != nil {
+                    l.reportError(ErrorSourceHook, fmt.Errorf("rotation callback panic: %v", r))
+                }
+            }()
+            fn()
+        }()
+    }
+}
+
+// stop runs the pending callbacks and stops the goroutine.
+func (cb *fileCallbacks) stop() {
+    cb.Lock()
+    if cb.closed {
+        cb.Unlock()
+        return
+    }
+    cb.closed = true
+    close(cb.wake)
+    cb.Unlock()
+    <-cb.done
+}
@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileLoggerCallbacks(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.log")
+	l := NewFileLogger(file, false, false, false, false)
+	defer l.Close()
+	l.SetMaxNumFiles(2)
+
+	rotations := make(chan RotateEvent, 10)
+	purges := make(chan []string, 10)
+	if err := l.OnRotate(func(ev RotateEvent) {
+		// Calling back into the logger must not deadlock.
+		l.Noticef("uploaded %s", filepath.Base(ev.Backup))
+		rotations <- ev
+	}); err != nil {
+		t.Fatalf("Failed to set OnRotate: %v", err)
+	}
+	if err := l.OnPurge(func(removed []string) { purges <- removed }); err != nil {
+		t.Fatalf("Failed to set OnPurge: %v", err)
+	}
+
+	l.Noticef("first file")
+	bak1, _ := l.Rotate()
+	st, err := os.Stat(bak1)
+	if err != nil {
+		t.Fatalf("Backup missing: %v", err)
+	}
+	bak2, _ := l.Rotate()
+
+	var ev RotateEvent
+	select {
+	case ev = <-rotations:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for OnRotate")
+	}
+	if ev.Backup != bak1 || ev.Path != file || ev.BackupSize != st.Size() || ev.Size == 0 {
+		t.Errorf("Unexpected rotate event %+v, backup size %d", ev, st.Size())
+	}
+	select {
+	case ev = <-rotations:
+		if ev.Backup != bak2 {
+			t.Errorf("Expected events in order, got %+v", ev)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the second OnRotate")
+	}
+	select {
+	case removed := <-purges:
+		if len(removed) != 1 || removed[0] != bak1 {
+			t.Errorf("Unexpected purged files %v, expected %s", removed, bak1)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for OnPurge")
+	}
+}
+
+func TestFileLoggerCallbacks_CloseRunsPending(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.log")
+	l := NewFileLogger(file, false, false, false, false)
+	l.SetErrorHandler(func(string, error) {})
+
+	calls := 0
+	l.OnRotate(func(RotateEvent) {
+		time.Sleep(10 * time.Millisecond)
+		calls++
+	})
+	l.Rotate()
+	l.Rotate()
+	l.Close()
+	if calls != 2 {
+		t.Errorf("Expected pending callbacks to run before Close returns, got %d", calls)
+	}
+
+	if err := NewSinkLogger(false, false).OnRotate(func(RotateEvent) {}); err == nil {
+		t.Error("Expected error for logger without file")
+	}
+}
@@ -0,0 +1,308 @@
+package logger
+
+import (
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "sync"
+    "time"
+)
+
+const (
+    defaultFallbackFailures = 3
+    defaultFallbackProbe    = 10 * time.Second
+)
+
+// FileFallback receives the output of a file logger while its log file
+// cannot be written. Name describes it in the gap record written to the
+// log file once it is writable again.
+type FileFallback interface {
+    io.Writer
+    Name() string
+}
+
+// NewStderrFallback returns a fallback writing to stderr.
+func NewStderrFallback() FileFallback {
+    return stderrFallback{}
+}
+
+type stderrFallback struct{}
+
+func (stderrFallback) Write(b []byte) (int, error) { return os.Stderr.Write(b) }
+func (stderrFallback) Name() string                { return "stderr" }
+
+// DirFallback writes to a file with the log file's name in another
+// directory, typically on a different disk.
+type DirFallback struct {
+    sync.Mutex
+    dir     string
+    name    string
+    dirMode os.FileMode
+    open    func(name string) (*os.File, error)
+    file    *os.File
+}
+
+// NewDirFallback returns a fallback writing to the directory dir, which
+// is created if needed when the fallback is first used.
+func NewDirFallback(dir string) *DirFallback {
+    return &DirFallback{dir: dir}
+}
+
+// bind sets the name of the primary log file, and how the logger creates
+// directories and opens files, so that the fallback file gets the same
+// permissions and owner.
+func (f *DirFallback) bind(primary string, dirMode os.FileMode, open func(string) (*os.File, error)) {
+    f.Lock()
+    defer f.Unlock()
+    f.name = filepath.Join(f.dir, filepath.Base(primary))
+    f.dirMode, f.open = dirMode, open
+}
+
+// Write implements io.Writer, opening the file on first use.
+func (f *DirFallback) Write(b []byte) (int, error) {
+    f.Lock()
+    defer f.Unlock()
+    if f.file == nil {
+        dirMode := f.dirMode
+        if dirMode == 0 {
+            dirMode = 0750
+        }
+        if err := os.MkdirAll(f.dir, dirMode); err != nil {
+            return 0, fmt.Errorf("unable to create fallback directory: %w", err)
+        }
+        open := f.open
+        if open == nil {
+            open = func(name string) (*os.File, error) {
+                return os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, defaultLogPerms)
+            }
+        }
+        file, err := open(f.name)
+        if err != nil {
+            return 0, fmt.Errorf("unable to open fallback log file: %w", err)
+        }
+        f.file = file
+    }
+    return f.file.Write(b)
+}
+
+// Name returns the fallback file name.
+func (f *DirFallback) Name() string {
+    f.Lock()
+    defer f.Unlock()
+    return f.name
+}
+
+// Close closes the fallback file.
+func (f *DirFallback) Close() error {
+    f.Lock()
+    defer f.Unlock()
+    if f.file == nil {
+        return nil
+    }
+    err := f.file.Close()
+    f.file = nil
+    return err
+}
+
+// RingFallback keeps the most recent lines in memory. They are written
+// to the log file after the gap record when it is writable again.
+type RingFallback struct {
+    sync.Mutex
+    lines [][]byte
+    next  int
+    full  bool
+}
+
+// NewRingFallback returns a fallback keeping the last size lines.
+func NewRingFallback(size int) *RingFallback {
+    return &RingFallback{lines: make([][]byte, max(size, 1))}
+}
+
+// Write implements io.Writer.
+func (r *RingFallback) Write(b []byte) (int, error) {
+    r.Lock()
+    defer r.Unlock()
+    r.lines[r.next] = append([]byte(nil), b...)
+    r.next = (r.next + 1) % len(r.lines)
+    if r.next == 0 {
+        r.full = true
+    }
+    return len(b), nil
+}
+
+// Name implements FileFallback.
+func (r *RingFallback) Name() string { return "memory" }
+
+// Lines returns the buffered lines, oldest first.
+func (r *RingFallback) Lines() []string {
+    r.Lock()
+    defer r.Unlock()
+    var lines []string
+    for _, b := range r.ordered() {
+        lines = append(lines, string(b))
+    }
+    return lines
+}
+
+// ordered returns the buffered lines oldest first. Lock must be held.
+func (r *RingFallback) ordered() [][]byte {
+    if !r.full {
+        return r.lines[:r.next]
+    }
+    return append(append([][]byte(nil), r.lines[r.next:]...), r.lines[:r.next]...)
+}
+
+// drain returns and forgets the buffered lines.
+func (r *RingFallback) drain() [][]byte {
+    r.Lock()
+    defer r.Unlock()
+    lines := r.ordered()
+    r.lines = make([][]byte, len(r.lines))
+    r.next, r.full = 0, false
+    return lines
+}
+
+// fileFallbackState tracks the use of a fallback by a FileLogger.
+type fileFallbackState struct {
+    fb            FileFallback
+    maxFailures   int
+    probeInterval time.Duration
+    failures      int
+    active        bool
+    since         time.Time
+    lastProbe     time.Time
+    entries       int // written to the fallback since the first failure
+}
+
+// SetFallback sets where the output goes when the log file cannot be
+// written: after failures consecutive write errors the fallback takes
+// over, and the log file is probed again every probeInterval. Once it is
+// writable, a record of the gap is written to it. Zero values select 3
+// failures and 10 seconds.
+func (l *Logger) SetFallback(fb FileFallback, failures int, probeInterval time.Duration) error {
+    if failures < 0 || probeInterval < 0 {
+        return fmt.Errorf("invalid fallback policy")
+    }
+    l.Lock()
+    if l.fl == nil {
+        l.Unlock()
+        return fmt.Errorf("can set fallback only for file logger")
+    }
+    fl := l.fl
+    l.Unlock()
+    fl.setFallback(fb, failures, probeInterval)
+    return nil
+}
+
+func (fl *FileLogger) setFallback(fb FileFallback, failures int, probeInterval time.Duration) {
+    fl.Lock()
+    defer fl.Unlock()
+    if fb == nil {
+        fl.fallback = nil
+        return
+    }
+    if b, ok := fb.(*DirFallback); ok {
+        b.bind(fl.file.Name(), fl.dirMode, fl.openLogFile)
+    }
+    if failures == 0 {
+        failures = defaultFallbackFailures
+    }
+    if probeInterval == 0 {
+        probeInterval = defaultFallbackProbe
+    }
+    fl.fallback = &fileFallbackState{fb: fb, maxFailures: failures, probeInterval: probeInterval}
+}
+
+// writeFile writes to the log file, or to the fallback while the log
+// file is failing, and accounts for the size of the log file. Lock must
+// be held.
+func (fl *FileLogger) writeFile(b []byte) (int, error) {
+    fs := fl.fallback
+    if fs == nil {
+        n, err := fl.file.Write(b)
+        fl.currentSize += int64(n)
+        countBytes(n)
+        if err != nil {
+            countWriteError(ErrorSourceFile)
+        }
+        return n, err
+    }
+
+    now := fl.now()
+    if fs.active && now.Sub(fs.lastProbe) < fs.probeInterval {
+        fl.writeFallback(b)
+        return len(b), nil
+    }
+    if fs.entries > 0 {
+        // Probe the log file with the record of the gap.
+        if err := fl.writeGap(now); err != nil {
+            fl.writeFailed(now, err)
+            fl.writeFallback(b)
+            return len(b), nil
+        }
+    }
+
+    n, err := fl.file.Write(b)
+    fl.currentSize += int64(n)
+    countBytes(n)
+    if err != nil {
+        fl.writeFailed(now, err)
+        fl.writeFallback(b)
+        return len(b), nil
+    }
+    fs.failures = 0
+    return n, nil
+}
+
+// writeFailed records a failed write to the log file and switches to
+// the fallback after too many consecutive failures. Lock must be held.
+func (fl *FileLogger) writeFailed(now time.Time, err error) {
+    fs := fl.fallback
+    fs.failures++
+    fs.lastProbe = now
+    if fs.entries == 0 {
+        fs.since = now
+    }
+    countWriteError(ErrorSourceFile)
+    err = fmt.Errorf("error writing to log file: %w", err)
+    if !fs.active && fs.failures >= fs.maxFailures {
+        fs.active = true
+        err = fmt.Errorf("%w; switching to fallback %s", err, fs.fb.Name())
+    }
+    fl.logger.reportError(ErrorSourceFile, err)
+}
+
+// writeFallback writes to the fallback. Lock must be held.
+func (fl *FileLogger) writeFallback(b []byte) {
+    fs := fl.fallback
+    fs.entries++
+    if _, err := fs.fb.Write(b); err != nil {
+        countWriteError(ErrorSourceFile)
+        fl.logger.reportError(ErrorSourceFile, fmt.Errorf("error writing to fallback %s: %w", fs.fb.Name(), err))
+    }
+}
+
+// writeGap writes the record of the entries sent to the fallback to the
+// log file and, when that succeeds, switches back to the log file. Lock
+// must be held.
+func (fl *FileLogger) writeGap(now time.Time) error {
+    fs := fl.fallback
+    line := fl.formatDirect(fl.logger.warnLabel, "Log file was unavailable from %s to %s, %d entries written to %s",
+        fs.since.Format(time.RFC3339), now.Format(time.RFC3339), fs.entries, fs.fb.Name())
+    n, err := fl.file.Write(line)
+    fl.currentSize += int64(n)
+    countBytes(n)
+    if err != nil {
+        return err
+    }
+    fs.active, fs.failures, fs.entries = false, 0, 0
+    if r, ok := fs.fb.(interface{ drain() [][]byte }); ok {
+        for _, b := range r.drain() {
+            n, _ := fl.file.Write(b)
+            fl.currentSize += int64(n)
+            countBytes(n)
+        }
+    }
+    return nil
+}
@@ -0,0 +1,165 @@
+package logger
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Helper log file that fails writes while broken is set.
+type flakyFile struct {
+	sync.Mutex
+	*os.File
+	broken bool
+}
+
+func (f *flakyFile) setBroken(broken bool) {
+	f.Lock()
+	defer f.Unlock()
+	f.broken = broken
+}
+
+func (f *flakyFile) Write(b []byte) (int, error) {
+	f.Lock()
+	defer f.Unlock()
+	if f.broken {
+		return 0, errors.New("no space left on device")
+	}
+	return f.File.Write(b)
+}
+
+func newFlakyFileLogger(t *testing.T, file string, opts ...LogOption) (*Logger, *flakyFile) {
+	t.Helper()
+	l := NewFileLogger(file, false, false, false, false, opts...)
+	t.Cleanup(func() { l.Close() })
+	l.SetErrorHandler(func(string, error) {})
+	l.fl.Lock()
+	ff := &flakyFile{File: l.fl.file.(*os.File)}
+	l.fl.file = ff
+	l.fl.Unlock()
+	return l, ff
+}
+
+func TestFileLoggerFallback_Ring(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.log")
+	l, ff := newFlakyFileLogger(t, file)
+	ring := NewRingFallback(10)
+	if err := l.SetFallback(ring, 2, 30*time.Millisecond); err != nil {
+		t.Fatalf("Failed to set fallback: %v", err)
+	}
+
+	l.Noticef("before")
+	ff.setBroken(true)
+	l.Noticef("lost 1")
+	l.Noticef("lost 2")
+	ff.setBroken(false)
+	l.Noticef("lost 3") // the file is not probed before the interval
+	if lines := ring.Lines(); len(lines) != 3 || !strings.Contains(lines[2], "lost 3") {
+		t.Fatalf("Expected entries to go to the fallback, got %q", lines)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	l.Noticef("after")
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Error reading log file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("Expected 6 lines in the log file, got:\n%s", content)
+	}
+	if !strings.Contains(lines[1], "[WRN] Log file was unavailable from") ||
+		!strings.Contains(lines[1], "3 entries written to memory") {
+		t.Errorf("Expected gap record, got %q", lines[1])
+	}
+	for i, want := range []string{"before", "", "lost 1", "lost 2", "lost 3", "after"} {
+		if !strings.Contains(lines[i], want) {
+			t.Errorf("Line %d: expected %q, got %q", i, want, lines[i])
+		}
+	}
+	if len(ring.Lines()) != 0 {
+		t.Error("Expected the ring to be emptied once replayed")
+	}
+}
+
+func TestFileLoggerFallback_Dir(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "test.log")
+	altDir := filepath.Join(tmpDir, "alt")
+	l, ff := newFlakyFileLogger(t, file)
+	if err := l.SetFallback(NewDirFallback(altDir), 1, time.Hour); err != nil {
+		t.Fatalf("Failed to set fallback: %v", err)
+	}
+
+	ff.setBroken(true)
+	l.Errorf("disk full")
+	ff.setBroken(false)
+	l.Errorf("still in fallback")
+
+	content, err := os.ReadFile(filepath.Join(altDir, "test.log"))
+	if err != nil {
+		t.Fatalf("Error reading fallback file: %v", err)
+	}
+	if !strings.Contains(string(content), "disk full") || !strings.Contains(string(content), "still in fallback") {
+		t.Errorf("Unexpected fallback content:\n%s", content)
+	}
+}
+
+func TestFileLoggerFallback_DirOptions(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "test.log")
+	altDir := filepath.Join(tmpDir, "alt")
+	l, ff := newFlakyFileLogger(t, file, LogFileMode(0600), LogDirMode(0700))
+	if err := l.SetFallback(NewDirFallback(altDir), 1, time.Hour); err != nil {
+		t.Fatalf("Failed to set fallback: %v", err)
+	}
+
+	ff.setBroken(true)
+	l.Errorf("disk full")
+
+	// The fallback uses the logger's modes rather than the defaults.
+	info, err := os.Stat(altDir)
+	if err != nil {
+		t.Fatalf("Error reading fallback directory: %v", err)
+	}
+	if info.Mode().Perm() != 0700 {
+		t.Errorf("Expected fallback directory mode 0700, got %v", info.Mode().Perm())
+	}
+	info, err = os.Stat(filepath.Join(altDir, "test.log"))
+	if err != nil {
+		t.Fatalf("Error reading fallback file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected fallback file mode 0600, got %v", info.Mode().Perm())
+	}
+}
+
+func TestFileLoggerFallback_TransientFailure(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.log")
+	l, ff := newFlakyFileLogger(t, file)
+	ring := NewRingFallback(10)
+	l.SetFallback(ring, 3, time.Hour)
+
+	ff.setBroken(true)
+	l.Noticef("one failure")
+	ff.setBroken(false)
+	l.Noticef("recovered")
+
+	content, _ := os.ReadFile(file)
+	if !strings.Contains(string(content), "1 entries written to memory") ||
+		!strings.Contains(string(content), "one failure") {
+		t.Errorf("Expected the entry below the failure threshold to be kept, got:\n%s", content)
+	}
+}
+
+func TestSetFallback_NotFileLogger(t *testing.T) {
+	l := NewSinkLogger(false, false)
+	if err := l.SetFallback(NewStderrFallback(), 0, 0); err == nil {
+		t.Error("Expected error for logger without file")
+	}
+}
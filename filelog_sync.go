@@ -0,0 +1,220 @@
+package logger
+
+import (
+    "fmt"
+    "os"
+    "runtime"
+    "sync"
+    "time"
+)
+
+// SyncMode selects when a file logger flushes the log file to disk.
+type SyncMode int
+
+const (
+    // SyncNever leaves flushing to the operating system.
+    SyncNever SyncMode = iota
+    // SyncEveryWrite flushes after every entry, before the logging call
+    // returns. Buffered output is written with it, so a BufferPolicy has
+    // no effect.
+    SyncEveryWrite
+    // SyncEveryN flushes after the number of entries or bytes set in the
+    // policy.
+    SyncEveryN
+    // SyncInterval flushes periodically from a background goroutine.
+    SyncInterval
+)
+
+// SyncPolicy sets when a file logger calls fsync on the log file. It can
+// be passed to NewFileLogger as an option or set with SetSyncPolicy.
+// With any mode but SyncNever, the directory is synced too after
+// rotations, so that the renamed backup and the new file survive a crash.
+type SyncPolicy struct {
+    Mode     SyncMode
+    Entries  int           // SyncEveryN: sync after this many entries
+    Bytes    int64         // SyncEveryN: sync after this many bytes
+    Interval time.Duration // SyncInterval: sync this often
+}
+
+func (p SyncPolicy) isLoggerOption() {}
+
+func (p SyncPolicy) validate() error {
+    switch p.Mode {
+    case SyncNever, SyncEveryWrite:
+    case SyncEveryN:
+        if p.Entries < 0 || p.Bytes < 0 || (p.Entries == 0 && p.Bytes == 0) {
+            return fmt.Errorf("invalid sync policy: %+v", p)
+        }
+    case SyncInterval:
+        if p.Interval <= 0 {
+            return fmt.Errorf("invalid sync policy: %+v", p)
+        }
+    default:
+        return fmt.Errorf("unknown sync mode %d", p.Mode)
+    }
+    return nil
+}
+
+// fileSync tracks the writes since the last sync of a FileLogger.
+type fileSync struct {
+    policy  SyncPolicy
+    entries int
+    bytes   int64
+    ticker  *fileTicker
+}
+
+// fileTicker calls a function periodically on its own goroutine.
+type fileTicker struct {
+    done    chan struct{}
+    stopped chan struct{}
+    once    sync.Once
+}
+
+func startFileTicker(interval time.Duration, fn func()) *fileTicker {
+    t := &fileTicker{
+        done:    make(chan struct{}),
+        stopped: make(chan struct{}),
+    }
+    go func() {
+        defer close(t.stopped)
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+        for {
+            select {
+            case <-ticker.C:
+                fn()
+            case <-t.done:
+                return
+            }
+        }
+    }()
+    return t
+}
+
+// stop stops the goroutine and waits for it to exit. t may be nil. It
+// must be called without the FileLogger lock, which fn may be waiting for.
+func (t *fileTicker) stop() {
+    if t == nil {
+        return
+    }
+    t.once.Do(func() { close(t.done) })
+    <-t.stopped
+}
+
+// SetSyncPolicy sets when the log file is synced to disk.
+func (l *Logger) SetSyncPolicy(p SyncPolicy) error {
+    if err := p.validate(); err != nil {
+        return err
+    }
+    l.Lock()
+    if l.fl == nil {
+        l.Unlock()
+        return fmt.Errorf("can set sync policy only for file logger")
+    }
+    fl := l.fl
+    l.Unlock()
+    fl.setSyncPolicy(p)
+    return nil
+}
+
+// Sync commits the log file to disk. It does nothing for loggers
+// without a log file.
+func (l *Logger) Sync() error {
+    l.Lock()
+    fl := l.fl
+    l.Unlock()
+    if fl == nil {
+        return nil
+    }
+    fl.Lock()
+    defer fl.Unlock()
+    if fl.isClosed {
+        return fmt.Errorf("log file is closed")
+    }
+    return fl.sync()
+}
+
+func (fl *FileLogger) setSyncPolicy(p SyncPolicy) {
+    fl.Lock()
+    old := fl.syncer
+    fl.syncer = nil
+    if p.Mode != SyncNever && !fl.isClosed {
+        s := &fileSync{policy: p}
+        if p.Mode == SyncInterval {
+            s.ticker = startFileTicker(p.Interval, func() { fl.syncTick(s) })
+        }
+        fl.syncer = s
+    }
+    fl.Unlock()
+    if old != nil {
+        old.ticker.stop()
+    }
+}
+
+// syncTick syncs the log file if it was written since the last sync.
+func (fl *FileLogger) syncTick(s *fileSync) {
+    fl.Lock()
+    defer fl.Unlock()
+    if s.entries > 0 {
+        if err := fl.sync(); err != nil && fl.logger != nil {
+            fl.logger.reportError(ErrorSourceFile, err)
+        }
+    }
+}
+
+// sync writes buffered output and commits the log file to disk. Lock
+// must be held.
+func (fl *FileLogger) sync() error {
+    if err := fl.flushBuffer(); err != nil {
+        return err
+    }
+    if fl.syncer != nil {
+        fl.syncer.entries, fl.syncer.bytes = 0, 0
+    }
+    f, ok := fl.file.(interface{ Sync() error })
+    if !ok {
+        return nil
+    }
+    if err := f.Sync(); err != nil {
+        return fmt.Errorf("error syncing log file: %w", err)
+    }
+    return nil
+}
+
+// syncWritten accounts for an entry of n bytes and syncs when the policy
+// requires it. Lock must be held.
+func (fl *FileLogger) syncWritten(n int) error {
+    s := fl.syncer
+    if s == nil || (fl.fallback != nil && fl.fallback.active) {
+        return nil
+    }
+    s.entries++
+    s.bytes += int64(n)
+    switch s.policy.Mode {
+    case SyncEveryWrite:
+        return fl.sync()
+    case SyncEveryN:
+        if (s.policy.Entries > 0 && s.entries >= s.policy.Entries) ||
+            (s.policy.Bytes > 0 && s.bytes >= s.policy.Bytes) {
+            return fl.sync()
+        }
+    }
+    return nil
+}
+
+// syncDir commits the directory entries of dir to disk, after renames.
+func syncDir(dir string) error {
+    if runtime.GOOS == "windows" {
+        // Directories cannot be synced on Windows.
+        return nil
+    }
+    d, err := os.Open(dir)
+    if err != nil {
+        return fmt.Errorf("unable to open log directory for sync: %w", err)
+    }
+    defer d.Close()
+    if err := d.Sync(); err != nil {
+        return fmt.Errorf("error syncing log directory: %w", err)
+    }
+    return nil
+}
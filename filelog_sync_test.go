@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Helper log file that counts syncs.
+type syncCountingFile struct {
+	sync.Mutex
+	*os.File
+	syncs int
+}
+
+func (f *syncCountingFile) Sync() error {
+	f.Lock()
+	defer f.Unlock()
+	f.syncs++
+	return f.File.Sync()
+}
+
+func (f *syncCountingFile) count() int {
+	f.Lock()
+	defer f.Unlock()
+	return f.syncs
+}
+
+func newSyncCountingLogger(t *testing.T, opts ...LogOption) (*Logger, *syncCountingFile) {
+	t.Helper()
+	l := NewFileLogger(filepath.Join(t.TempDir(), "test.log"), false, false, false, false, opts...)
+	t.Cleanup(func() { l.Close() })
+	l.fl.Lock()
+	sf := &syncCountingFile{File: l.fl.file.(*os.File)}
+	l.fl.file = sf
+	l.fl.Unlock()
+	return l, sf
+}
+
+func TestFileLoggerSync_EveryWrite(t *testing.T) {
+	l, sf := newSyncCountingLogger(t, SyncPolicy{Mode: SyncEveryWrite})
+	l.Noticef("one")
+	l.Noticef("two")
+	if n := sf.count(); n != 2 {
+		t.Errorf("Expected 2 syncs, got %d", n)
+	}
+}
+
+func TestFileLoggerSync_EveryN(t *testing.T) {
+	l, sf := newSyncCountingLogger(t)
+	if err := l.SetSyncPolicy(SyncPolicy{Mode: SyncEveryN, Entries: 3}); err != nil {
+		t.Fatalf("Failed to set sync policy: %v", err)
+	}
+	for i := 0; i < 7; i++ {
+		l.Noticef("entry %d", i)
+	}
+	if n := sf.count(); n != 2 {
+		t.Errorf("Expected 2 syncs after 7 entries, got %d", n)
+	}
+
+	if err := l.SetSyncPolicy(SyncPolicy{Mode: SyncEveryN, Bytes: 1}); err != nil {
+		t.Fatalf("Failed to set sync policy: %v", err)
+	}
+	l.Noticef("bytes")
+	if n := sf.count(); n != 3 {
+		t.Errorf("Expected a sync after the byte threshold, got %d syncs", n)
+	}
+}
+
+func TestFileLoggerSync_Interval(t *testing.T) {
+	l, sf := newSyncCountingLogger(t)
+	if err := l.SetSyncPolicy(SyncPolicy{Mode: SyncInterval, Interval: 10 * time.Millisecond}); err != nil {
+		t.Fatalf("Failed to set sync policy: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := sf.count(); n != 0 {
+		t.Errorf("Expected no sync without writes, got %d", n)
+	}
+	l.Noticef("entry")
+	deadline := time.Now().Add(time.Second)
+	for sf.count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if sf.count() == 0 {
+		t.Error("Expected the ticker to sync the log file")
+	}
+	l.Close()
+	if err := l.Sync(); err == nil {
+		t.Error("Expected error syncing a closed log file")
+	}
+}
+
+func TestFileLoggerSync_Rotate(t *testing.T) {
+	l, sf := newSyncCountingLogger(t, SyncPolicy{Mode: SyncEveryN, Entries: 100})
+	l.Noticef("before rotation")
+	if _, err := l.Rotate(); err != nil {
+		t.Fatalf("Failed to rotate: %v", err)
+	}
+	if n := sf.count(); n != 1 {
+		t.Errorf("Expected the log file to be synced before rotation, got %d syncs", n)
+	}
+	if err := l.Sync(); err != nil {
+		t.Errorf("Failed to sync after rotation: %v", err)
+	}
+}
+
+func TestFileLoggerSync_InvalidPolicy(t *testing.T) {
+	l, _ := newSyncCountingLogger(t)
+	for _, p := range []SyncPolicy{
+		{Mode: SyncEveryN},
+		{Mode: SyncInterval},
+		{Mode: SyncMode(42)},
+	} {
+		if err := l.SetSyncPolicy(p); err == nil {
+			t.Errorf("Expected error for policy %+v", p)
+		}
+	}
+	if err := newTestStdLogger(false, false, false, false, false).SetSyncPolicy(SyncPolicy{Mode: SyncEveryWrite}); err == nil {
+		t.Error("Expected error setting a sync policy without log file")
+	}
+	if err := newTestStdLogger(false, false, false, false, false).Sync(); err != nil {
+		t.Errorf("Expected Sync to do nothing without log file, got %v", err)
+	}
+}
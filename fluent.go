@@ -0,0 +1,294 @@
+package logger
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFluentBatchSize     = 100
+	defaultFluentFlushInterval = time.Second
+	defaultFluentAckTimeout    = 5 * time.Second
+	// Maximum number of entries kept while the destination is unreachable.
+	defaultFluentMaxPending = 10000
+	// Retry delays after a failed delivery.
+	minFluentRetryDelay = 100 * time.Millisecond
+	maxFluentRetryDelay = 30 * time.Second
+)
+
+// FluentSink ships entries to Fluentd or fluent-bit using the Forward
+// protocol. Entries are batched and sent in PackedForward mode, either
+// when the batch is full or when the flush interval elapses. With acks
+// enabled a batch is kept and resent until the server acknowledges it,
+// giving at-least-once delivery.
+type FluentSink struct {
+	sync.Mutex
+	// Serializes flushes, the connection is only used while held.
+	flushMu       sync.Mutex
+	network       string
+	addr          string
+	tag           string
+	conn          net.Conn
+	reader        *bufio.Reader
+	pending       []byte
+	count         int
+	batchSize     int
+	maxPending    int
+	flushInterval time.Duration
+	requireAck    bool
+	ackTimeout    time.Duration
+	retryDelay    time.Duration
+	nextRetry     time.Time
+	kick          chan struct{}
+	quit          chan struct{}
+	done          chan struct{}
+	closed        bool
+}
+
+// NewFluentSink creates a sink for the Forward input at addr, given as
+// "tcp://host:port" or "unix:///path/to/socket". Every entry is sent
+// with the given tag. The connection is established lazily.
+func NewFluentSink(addr, tag string) (*FluentSink, error) {
+	network, destination, err := parseAddress(addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fluent address: %v", err)
+	}
+	if network != "tcp" && network != "unix" {
+		return nil, fmt.Errorf("invalid network type for fluent: %q", network)
+	}
+	if tag == "" {
+		return nil, fmt.Errorf("fluent tag must not be empty")
+	}
+
+	s := &FluentSink{
+		network:       network,
+		addr:          destination,
+		tag:           tag,
+		batchSize:     defaultFluentBatchSize,
+		maxPending:    defaultFluentMaxPending,
+		flushInterval: defaultFluentFlushInterval,
+		ackTimeout:    defaultFluentAckTimeout,
+		kick:          make(chan struct{}, 1),
+		quit:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// SetBatchSize sets the number of entries that triggers an immediate flush.
+func (s *FluentSink) SetBatchSize(n int) {
+	s.Lock()
+	defer s.Unlock()
+	s.batchSize = max(n, 1)
+}
+
+// SetFlushInterval sets the maximum time entries are held before
+// being sent. A value that is not positive restores the default of one
+// second.
+func (s *FluentSink) SetFlushInterval(d time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+	if d <= 0 {
+		d = defaultFluentFlushInterval
+	}
+	s.flushInterval = d
+}
+
+// SetMaxPending sets the number of entries buffered while the
+// destination is unreachable, further entries are dropped.
+func (s *FluentSink) SetMaxPending(n int) {
+	s.Lock()
+	defer s.Unlock()
+	s.maxPending = max(n, 1)
+}
+
+// SetRequireAck enables acknowledgements. The timeout bounds how long
+// the sink waits for an ack before the batch is considered lost and
+// sent again.
+func (s *FluentSink) SetRequireAck(ack bool, timeout time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+	s.requireAck = ack
+	if timeout > 0 {
+		s.ackTimeout = timeout
+	}
+}
+
+// fluentRecord builds the record map for an entry.
+func fluentRecord(e *Entry) map[string]any {
+	rec := make(map[string]any, len(e.Fields)+4)
+	for k, v := range e.Fields {
+		rec[k] = v
+	}
+	rec["message"] = e.Message
+	rec["level"] = e.Level.String()
+	rec["host"] = e.Host
+	rec["pid"] = e.PID
+	return rec
+}
+
+// WriteEntry implements the Sink interface. Entries are queued and
+// sent asynchronously.
+func (s *FluentSink) WriteEntry(e *Entry) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.closed {
+		return fmt.Errorf("fluent sink is closed")
+	}
+	if s.count >= s.maxPending {
+		return fmt.Errorf("fluent buffer full, entry dropped")
+	}
+	s.pending = appendMsgpackArrayHeader(s.pending, 2)
+	s.pending = appendMsgpackEventTime(s.pending, e.Time)
+	s.pending = appendMsgpackMap(s.pending, fluentRecord(e))
+	s.count++
+	if s.count >= s.batchSize {
+		select {
+		case s.kick <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (s *FluentSink) run() {
+	defer close(s.done)
+	for {
+		s.Lock()
+		interval := s.flushInterval
+		s.Unlock()
+		timer := time.NewTimer(interval)
+		select {
+		case <-s.kick:
+		case <-timer.C:
+		case <-s.quit:
+			timer.Stop()
+			return
+		}
+		timer.Stop()
+		if err := s.flush(false); err != nil {
+			countWriteError(ErrorSourceSink)
+			reportError(nil, ErrorSourceSink, fmt.Errorf("failed to write to fluent: %w", err))
+		}
+	}
+}
+
+// flush sends pending entries. Unless forced, nothing is sent while a
+// previous failure is backing off.
+func (s *FluentSink) flush(force bool) error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.Lock()
+	if s.count == 0 || (!force && time.Now().Before(s.nextRetry)) {
+		s.Unlock()
+		return nil
+	}
+	entries, count := s.pending, s.count
+	s.pending, s.count = nil, 0
+	requireAck, ackTimeout := s.requireAck, s.ackTimeout
+	s.Unlock()
+
+	err := s.send(entries, count, requireAck, ackTimeout)
+
+	s.Lock()
+	defer s.Unlock()
+	if err == nil {
+		s.retryDelay = 0
+		s.nextRetry = time.Time{}
+		return nil
+	}
+	// Keep the batch ahead of anything logged in the meantime.
+	s.pending = append(entries, s.pending...)
+	s.count += count
+	s.retryDelay = min(max(2*s.retryDelay, minFluentRetryDelay), maxFluentRetryDelay)
+	s.nextRetry = time.Now().Add(s.retryDelay)
+	return err
+}
+
+// send writes a PackedForward message and waits for the ack if needed.
+// Must be called with flushMu held.
+func (s *FluentSink) send(entries []byte, count int, requireAck bool, ackTimeout time.Duration) error {
+	if s.conn == nil {
+		conn, err := net.Dial(s.network, s.addr)
+		if err != nil {
+			return fmt.Errorf("unable to connect to fluent: %w", err)
+		}
+		s.conn = conn
+		s.reader = bufio.NewReader(conn)
+	}
+
+	option := map[string]any{"size": count}
+	var chunk string
+	if requireAck {
+		var id [16]byte
+		if _, err := rand.Read(id[:]); err != nil {
+			return fmt.Errorf("unable to generate fluent chunk id: %w", err)
+		}
+		chunk = base64.StdEncoding.EncodeToString(id[:])
+		option["chunk"] = chunk
+	}
+
+	msg := appendMsgpackArrayHeader(nil, 3)
+	msg = appendMsgpackString(msg, s.tag)
+	msg = appendMsgpackBin(msg, entries)
+	msg = appendMsgpackMap(msg, option)
+	if _, err := s.conn.Write(msg); err != nil {
+		s.disconnect()
+		return fmt.Errorf("error writing to fluent: %w", err)
+	}
+	if !requireAck {
+		return nil
+	}
+
+	s.conn.SetReadDeadline(time.Now().Add(ackTimeout))
+	resp, err := readMsgpack(s.reader)
+	if err != nil {
+		s.disconnect()
+		return fmt.Errorf("error reading fluent ack: %w", err)
+	}
+	s.conn.SetReadDeadline(time.Time{})
+	if m, ok := resp.(map[string]any); !ok || m["ack"] != chunk {
+		s.disconnect()
+		return fmt.Errorf("unexpected fluent ack: %v", resp)
+	}
+	return nil
+}
+
+func (s *FluentSink) disconnect() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn, s.reader = nil, nil
+	}
+}
+
+// Flush synchronously sends pending entries, ignoring any retry backoff.
+func (s *FluentSink) Flush() error {
+	return s.flush(true)
+}
+
+// Close stops the background sender, makes a final attempt to deliver
+// pending entries and closes the connection.
+func (s *FluentSink) Close() error {
+	s.Lock()
+	if s.closed {
+		s.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.Unlock()
+
+	close(s.quit)
+	<-s.done
+	err := s.flush(true)
+	s.flushMu.Lock()
+	s.disconnect()
+	s.flushMu.Unlock()
+	return err
+}
@@ -0,0 +1,259 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Helper fluent Forward server. When ack is set it acknowledges chunks,
+// except that the very first message on the first connection is dropped
+// without an ack when dropFirst is set.
+type testFluentServer struct {
+	ln        net.Listener
+	ack       bool
+	dropFirst bool
+	records   chan map[string]any
+	tags      chan string
+}
+
+func newTestFluentServer(t *testing.T, network, addr string, ack, dropFirst bool) *testFluentServer {
+	t.Helper()
+	ln, err := net.Listen(network, addr)
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	srv := &testFluentServer{
+		ln:        ln,
+		ack:       ack,
+		dropFirst: dropFirst,
+		records:   make(chan map[string]any, 100),
+		tags:      make(chan string, 100),
+	}
+	go srv.serve(t)
+	return srv
+}
+
+func (srv *testFluentServer) serve(t *testing.T) {
+	first := true
+	for {
+		c, err := srv.ln.Accept()
+		if err != nil {
+			return
+		}
+		r := bufio.NewReader(c)
+		for {
+			v, err := readMsgpack(r)
+			if err != nil {
+				break
+			}
+			msg, ok := v.([]any)
+			if !ok || len(msg) != 3 {
+				t.Errorf("unexpected forward message: %v", v)
+				break
+			}
+			option := msg[2].(map[string]any)
+			if first && srv.dropFirst {
+				first = false
+				break
+			}
+			entries := bytes.NewReader(msg[1].([]byte))
+			for entries.Len() > 0 {
+				e, err := readMsgpack(entries)
+				if err != nil {
+					t.Errorf("unable to decode entry: %v", err)
+					break
+				}
+				pair := e.([]any)
+				rec := pair[1].(map[string]any)
+				rec["@time"] = pair[0]
+				srv.tags <- msg[0].(string)
+				srv.records <- rec
+			}
+			if srv.ack {
+				c.Write(appendMsgpackMap(nil, map[string]any{"ack": option["chunk"]}))
+			}
+		}
+		c.Close()
+	}
+}
+
+func (srv *testFluentServer) expect(t *testing.T, messages ...string) []map[string]any {
+	t.Helper()
+	var recs []map[string]any
+	for _, msg := range messages {
+		select {
+		case rec := <-srv.records:
+			if rec["message"] != msg {
+				t.Fatalf("expected %q, got %v", msg, rec)
+			}
+			recs = append(recs, rec)
+		case <-time.After(3 * time.Second):
+			t.Fatalf("timed out waiting for %q", msg)
+		}
+	}
+	return recs
+}
+
+func TestFluentSink_Batching(t *testing.T) {
+	srv := newTestFluentServer(t, "tcp", "127.0.0.1:0", false, false)
+	s, err := NewFluentSink("tcp://"+srv.ln.Addr().String(), "app.logs")
+	if err != nil {
+		t.Fatalf("Failed to create fluent sink: %v", err)
+	}
+	s.SetBatchSize(2)
+	s.SetFlushInterval(time.Hour)
+	l := NewSinkLogger(false, false, s)
+	defer l.Close()
+
+	l.WithFields(Fields{"user": "bob", "attempt": 3}).Noticef("first")
+	select {
+	case rec := <-srv.records:
+		t.Fatalf("expected entry to be batched, got %v", rec)
+	case <-time.After(100 * time.Millisecond):
+	}
+	l.Errorf("second")
+
+	recs := srv.expect(t, "first", "second")
+	if recs[0]["user"] != "bob" || recs[0]["attempt"] != int64(3) || recs[0]["level"] != "info" {
+		t.Errorf("unexpected record: %v", recs[0])
+	}
+	if recs[1]["level"] != "error" || recs[1]["pid"] == nil || recs[1]["host"] == nil {
+		t.Errorf("unexpected record: %v", recs[1])
+	}
+	if ts, ok := recs[0]["@time"].(time.Time); !ok || time.Since(ts) > time.Minute {
+		t.Errorf("expected event time, got %v", recs[0]["@time"])
+	}
+	if tag := <-srv.tags; tag != "app.logs" {
+		t.Errorf("expected tag app.logs, got %q", tag)
+	}
+}
+
+func TestFluentSink_AckRetry(t *testing.T) {
+	srv := newTestFluentServer(t, "tcp", "127.0.0.1:0", true, true)
+	s, err := NewFluentSink("tcp://"+srv.ln.Addr().String(), "app")
+	if err != nil {
+		t.Fatalf("Failed to create fluent sink: %v", err)
+	}
+	s.SetRequireAck(true, 200*time.Millisecond)
+	s.SetFlushInterval(10 * time.Millisecond)
+	l := NewSinkLogger(false, false, s)
+	defer l.Close()
+
+	// The first delivery is dropped by the server, the sink must resend it.
+	l.Warnf("must arrive")
+	srv.expect(t, "must arrive")
+}
+
+func TestFluentSink_UnixAndClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fluent.sock")
+	srv := newTestFluentServer(t, "unix", path, true, false)
+	s, err := NewFluentSink("unix://"+path, "app")
+	if err != nil {
+		t.Fatalf("Failed to create fluent sink: %v", err)
+	}
+	s.SetRequireAck(true, time.Second)
+	s.SetFlushInterval(time.Hour)
+
+	s.WriteEntry(&Entry{Time: time.Now(), Message: "flushed on close"})
+	if err := s.Close(); err != nil {
+		t.Fatalf("Unexpected error on Close: %v", err)
+	}
+	srv.expect(t, "flushed on close")
+	if err := s.WriteEntry(&Entry{Message: "too late"}); err == nil {
+		t.Fatal("Expected error writing to a closed sink")
+	}
+}
+
+func TestFluentSink_MaxPending(t *testing.T) {
+	s, err := NewFluentSink("tcp://127.0.0.1:1", "app")
+	if err != nil {
+		t.Fatalf("Failed to create fluent sink: %v", err)
+	}
+	defer s.Close()
+	s.SetFlushInterval(time.Hour)
+	s.SetMaxPending(1)
+	if err := s.WriteEntry(&Entry{Message: "kept"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := s.WriteEntry(&Entry{Message: "dropped"}); err == nil {
+		t.Fatal("Expected error when the buffer is full")
+	}
+}
+
+func TestFluentSink_ClampedSettings(t *testing.T) {
+	s, err := NewFluentSink("tcp://127.0.0.1:1", "app")
+	if err != nil {
+		t.Fatalf("Failed to create fluent sink: %v", err)
+	}
+	defer s.Close()
+	s.SetFlushInterval(0)
+	s.SetMaxPending(0)
+	s.Lock()
+	interval, pending := s.flushInterval, s.maxPending
+	s.Unlock()
+	if interval != defaultFluentFlushInterval {
+		t.Errorf("Expected a zero flush interval to restore the default, got %v", interval)
+	}
+	if pending != 1 {
+		t.Errorf("Expected max pending to be at least 1, got %d", pending)
+	}
+	if err := s.WriteEntry(&Entry{Message: "kept"}); err != nil {
+		t.Errorf("Expected an entry to be buffered, got %v", err)
+	}
+}
+
+func TestNewFluentSink_InvalidArgs(t *testing.T) {
+	if _, err := NewFluentSink("udp://127.0.0.1:24224", "app"); err == nil {
+		t.Error("Expected error for udp address")
+	}
+	if _, err := NewFluentSink("tcp://127.0.0.1:24224", ""); err == nil {
+		t.Error("Expected error for empty tag")
+	}
+}
+
+func TestMsgpackOversizedLength(t *testing.T) {
+	for _, data := range [][]byte{
+		{0xdb, 0x40, 0x00, 0x00, 0x00}, // str 32 of 1 GiB
+		{0xdd, 0xff, 0xff, 0xff, 0xff}, // array 32
+		{0xdf, 0x10, 0x00, 0x00, 0x00}, // map 32
+	} {
+		if _, err := readMsgpack(bytes.NewReader(data)); err == nil || !strings.Contains(err.Error(), "exceeds") {
+			t.Errorf("Expected %x to be rejected, got %v", data, err)
+		}
+	}
+}
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	now := time.Unix(1700000000, 123456789)
+	in := map[string]any{
+		"nil": nil, "bool": true, "small": 5, "neg": -100, "big": int64(1) << 40,
+		"uint": uint64(1) << 63, "float": 1.5, "str": "hello", "long": string(bytes.Repeat([]byte("x"), 300)),
+		"bin": []byte{1, 2, 3}, "time": now, "list": []any{1, "two"}, "nested": Fields{"a": "b"},
+	}
+	out, err := readMsgpack(bytes.NewReader(appendMsgpack(nil, in)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	m := out.(map[string]any)
+	checks := map[string]any{
+		"nil": nil, "bool": true, "small": int64(5), "neg": int64(-100), "big": int64(1) << 40,
+		"uint": uint64(1) << 63, "float": 1.5, "str": "hello",
+	}
+	for k, v := range checks {
+		if m[k] != v {
+			t.Errorf("for %q expected %v (%T), got %v (%T)", k, v, v, m[k], m[k])
+		}
+	}
+	if len(m["long"].(string)) != 300 || !bytes.Equal(m["bin"].([]byte), []byte{1, 2, 3}) {
+		t.Errorf("unexpected string or binary: %v", m)
+	}
+	if !m["time"].(time.Time).Equal(now) || m["nested"].(map[string]any)["a"] != "b" || m["list"].([]any)[1] != "two" {
+		t.Errorf("unexpected composite values: %v", m)
+	}
+}
@@ -0,0 +1,164 @@
+package logger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timestamp layouts for LogTimestamp, besides any Go time layout.
+const (
+	// TimestampDefault is the layout used without LogTimestamp, with
+	// microseconds.
+	TimestampDefault = "2006/01/02 15:04:05.000000"
+	// TimestampRFC3339Nano is RFC 3339 with nanoseconds.
+	TimestampRFC3339Nano = time.RFC3339Nano
+	// TimestampISO8601 is ISO 8601 with milliseconds and the UTC offset.
+	TimestampISO8601 = "2006-01-02T15:04:05.000-07:00"
+	// TimestampEpoch writes Unix time in seconds.
+	TimestampEpoch = "epoch"
+	// TimestampEpochMillis writes Unix time in milliseconds.
+	TimestampEpochMillis = "epoch_ms"
+	// TimestampElapsed writes the seconds elapsed since the logger was
+	// created, with microseconds.
+	TimestampElapsed = "elapsed"
+)
+
+// LogTimestamp sets the layout of timestamps, a Go time layout or one of
+// the Timestamp presets. It has no effect unless timestamps are enabled.
+type LogTimestamp string
+
+func (t LogTimestamp) isLoggerOption() {}
+
+// LogTimezone sets the time zone of timestamps by IANA name, such as
+// "Europe/Berlin", instead of local time or UTC. An unknown name is
+// reported to the error handler and local time is used.
+type LogTimezone string
+
+func (z LogTimezone) isLoggerOption() {}
+
+// LogLineEnding sets the line terminator of the text output, "\n" by
+// default. It must end with a newline and contain no other, e.g. "\r\n".
+// NewFileLogger fails on an invalid line ending; other constructors
+// report it to the error handler and use "\n".
+type LogLineEnding string
+
+func (e LogLineEnding) isLoggerOption() {}
+
+func (e LogLineEnding) validate() error {
+	if !strings.HasSuffix(string(e), "\n") || strings.Count(string(e), "\n") != 1 {
+		return fmt.Errorf("invalid line ending %q", string(e))
+	}
+	return nil
+}
+
+// MultiLineMode selects how messages spanning several lines are written
+// to the text output. It is passed to the constructors as an option.
+type MultiLineMode int
+
+const (
+	// MultiLineKeep writes messages unchanged.
+	MultiLineKeep MultiLineMode = iota
+	// MultiLineEscape replaces line breaks with \n and \r escapes.
+	MultiLineEscape
+	// MultiLineIndent starts continuation lines with a tab.
+	MultiLineIndent
+	// MultiLineSplit writes every line as an entry of its own, with the
+	// same header.
+	MultiLineSplit
+)
+
+func (m MultiLineMode) isLoggerOption() {}
+
+var lineBreakEscaper = strings.NewReplacer("\r", `\r`, "\n", `\n`)
+
+// textFormat holds the options shaping the lines of the text output,
+// shared by the log.Logger output and the FileLogger's own records.
+type textFormat struct {
+	eol       string // line terminator without its final newline
+	multiLine MultiLineMode
+	stamp     bool
+	layout    string
+	loc       *time.Location // nil for local time
+	start     time.Time      // reference for TimestampElapsed
+}
+
+func newTextFormat(stamp bool, opts ...LogOption) textFormat {
+	f := textFormat{stamp: stamp, layout: TimestampDefault, start: clockNow(clockOption(opts...))}
+	var utc bool
+	for _, opt := range opts {
+		switch v := opt.(type) {
+		case LogUTC:
+			utc = bool(v)
+		case LogTimestamp:
+			f.layout = string(v)
+		case LogTimezone:
+			loc, err := time.LoadLocation(string(v))
+			if err != nil {
+				reportError(nil, ErrorSourceOutput, fmt.Errorf("invalid time zone: %w", err))
+				continue
+			}
+			f.loc = loc
+		case LogLineEnding:
+			if err := v.validate(); err != nil {
+				reportError(nil, ErrorSourceOutput, err)
+				continue
+			}
+			f.eol = strings.TrimSuffix(string(v), "\n")
+		case MultiLineMode:
+			f.multiLine = v
+		}
+	}
+	if utc && f.loc == nil {
+		f.loc = time.UTC
+	}
+	return f
+}
+
+// appendTimestamp appends the timestamp for t followed by a space, if
+// timestamps are enabled.
+func (f textFormat) appendTimestamp(b []byte, t time.Time) []byte {
+	if !f.stamp {
+		return b
+	}
+	switch f.layout {
+	case TimestampEpoch:
+		b = strconv.AppendInt(b, t.Unix(), 10)
+	case TimestampEpochMillis:
+		b = strconv.AppendInt(b, t.UnixMilli(), 10)
+	case TimestampElapsed:
+		b = strconv.AppendFloat(b, t.Sub(f.start).Seconds(), 'f', 6, 64)
+	default:
+		if f.loc != nil {
+			t = t.In(f.loc)
+		}
+		b = t.AppendFormat(b, f.layout)
+	}
+	return append(b, ' ')
+}
+
+// lines returns the lines to write for msg according to the multi-line
+// mode, without terminators.
+func (f textFormat) lines(msg string) []string {
+	// A single trailing newline is the terminator, as for log.Logger.
+	msg = strings.TrimSuffix(msg, "\n")
+	if f.eol != "" {
+		msg = strings.TrimSuffix(msg, "\r")
+	}
+	if f.multiLine == MultiLineKeep || !strings.ContainsAny(msg, "\r\n") {
+		return []string{msg}
+	}
+	switch f.multiLine {
+	case MultiLineEscape:
+		return []string{lineBreakEscaper.Replace(msg)}
+	case MultiLineIndent:
+		return []string{strings.Join(splitLines(msg), f.eol+"\n\t")}
+	default:
+		return splitLines(msg)
+	}
+}
+
+func splitLines(msg string) []string {
+	return strings.Split(strings.ReplaceAll(msg, "\r\n", "\n"), "\n")
+}
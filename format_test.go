@@ -0,0 +1,152 @@
+package logger
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTextFormatLines(t *testing.T) {
+	tests := []struct {
+		name   string
+		format textFormat
+		msg    string
+		want   []string
+	}{
+		{"keep", textFormat{}, "a\nb\n", []string{"a\nb"}},
+		{"escape", textFormat{multiLine: MultiLineEscape}, "a\r\nb\nc", []string{`a\r\nb\nc`}},
+		{"indent", textFormat{multiLine: MultiLineIndent}, "a\nb\r\nc", []string{"a\n\tb\n\tc"}},
+		{"indent crlf", textFormat{eol: "\r", multiLine: MultiLineIndent}, "a\nb", []string{"a\r\n\tb"}},
+		{"split", textFormat{multiLine: MultiLineSplit}, "a\r\nb\n", []string{"a", "b"}},
+		{"single line", textFormat{multiLine: MultiLineSplit}, "a", []string{"a"}},
+	}
+	for _, tt := range tests {
+		got := tt.format.lines(tt.msg)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestFileLoggerLineEnding(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.log")
+	l := NewFileLogger(file, true, false, false, false, LogLineEnding("\r\n"), MultiLineSplit)
+	defer l.Close()
+
+	l.Noticef("first\nsecond")
+	if _, err := l.Rotate(); err != nil {
+		t.Fatalf("Failed to rotate: %v", err)
+	}
+	l.Noticef("after rotation")
+
+	content := readLog(t, file)
+	lines := strings.SplitAfter(content, "\n")
+	lines = lines[:len(lines)-1]
+	if len(lines) != 2 {
+		t.Fatalf("Expected rotation notice and entry, got %q", content)
+	}
+	for _, line := range lines {
+		if !strings.HasSuffix(line, "\r\n") || strings.Count(line, "\r") != 1 {
+			t.Errorf("Expected line terminated by CRLF, got %q", line)
+		}
+	}
+}
+
+func TestInvalidLineEnding(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.log")
+	for _, eol := range []LogLineEnding{"", "\r", "\n\n", "\n\r"} {
+		if _, err := newFileLogger(file, "", false, eol); err == nil {
+			t.Errorf("Expected error for line ending %q", string(eol))
+		}
+	}
+
+	var c errorCollector
+	SetErrorHandler(c.handle)
+	defer SetErrorHandler(nil)
+	if f := newTextFormat(false, LogLineEnding("\r")); f.eol != "" {
+		t.Errorf("Expected the default line ending, got %q", f.eol)
+	}
+	if errs := c.Errors(); len(errs) != 1 || !strings.Contains(errs[0], "invalid line ending") {
+		t.Errorf("Expected the invalid line ending to be reported, got %v", errs)
+	}
+}
+
+func TestLoggerMultiLineSplit(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.log")
+	l := NewFileLogger(file, false, false, false, true, MultiLineSplit)
+	defer l.Close()
+
+	l.Errorf("failure:\ndetail 1\ndetail 2")
+	content := readLog(t, file)
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %q", content)
+	}
+	header := pidPrefix() + "[ERR] "
+	for _, line := range lines {
+		if !strings.HasPrefix(line, header) {
+			t.Errorf("Expected every line to start with %q, got %q", header, line)
+		}
+	}
+}
+
+func TestTextFormatTimestamp(t *testing.T) {
+	ts := time.Date(2024, 3, 5, 7, 8, 9, 123456789, time.UTC)
+	tests := []struct {
+		opts []LogOption
+		want string
+	}{
+		{nil, ts.Local().Format(TimestampDefault) + " "},
+		{[]LogOption{LogUTC(true)}, "2024/03/05 07:08:09.123456 "},
+		{[]LogOption{LogTimestamp(TimestampRFC3339Nano), LogUTC(true)}, "2024-03-05T07:08:09.123456789Z "},
+		{[]LogOption{LogTimestamp(TimestampISO8601), LogTimezone("Asia/Tokyo")}, "2024-03-05T16:08:09.123+09:00 "},
+		{[]LogOption{LogTimestamp(TimestampEpoch)}, "1709622489 "},
+		{[]LogOption{LogTimestamp(TimestampEpochMillis)}, "1709622489123 "},
+		{[]LogOption{LogTimestamp("15:04")}, ts.Local().Format("15:04") + " "},
+	}
+	for _, tt := range tests {
+		if got := string(newTextFormat(true, tt.opts...).appendTimestamp(nil, ts)); got != tt.want {
+			t.Errorf("%v: expected %q, got %q", tt.opts, tt.want, got)
+		}
+	}
+
+	f := newTextFormat(true, LogTimestamp(TimestampElapsed))
+	elapsed := string(f.appendTimestamp(nil, f.start.Add(1500*time.Millisecond)))
+	if elapsed != "1.500000 " {
+		t.Errorf("Expected elapsed seconds, got %q", elapsed)
+	}
+	if got := newTextFormat(false, LogTimestamp(TimestampEpoch)).appendTimestamp(nil, ts); len(got) != 0 {
+		t.Errorf("Expected no timestamp when disabled, got %q", got)
+	}
+}
+
+func TestFileLoggerTimestampConsistent(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.log")
+	l := NewFileLogger(file, true, false, false, false, LogTimestamp(TimestampRFC3339Nano), LogTimezone("America/New_York"))
+	defer l.Close()
+
+	l.Noticef("before rotation")
+	if _, err := l.Rotate(); err != nil {
+		t.Fatalf("Failed to rotate: %v", err)
+	}
+	l.Noticef("after rotation")
+
+	// The rotation notice is written by the FileLogger itself.
+	content := readLog(t, file)
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected rotation notice and entry, got %q", content)
+	}
+	for _, line := range lines {
+		stamp, _, _ := strings.Cut(line, " ")
+		ts, err := time.Parse(time.RFC3339Nano, stamp)
+		if err != nil {
+			t.Errorf("Expected RFC 3339 timestamp, got %q: %v", line, err)
+			continue
+		}
+		if _, offset := ts.Zone(); offset != -5*3600 && offset != -4*3600 {
+			t.Errorf("Expected New York offset, got %q", stamp)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// GELFCompression selects how GELF messages sent over UDP are compressed.
+type GELFCompression int
+
+const (
+	GELFCompressNone GELFCompression = iota
+	GELFCompressGzip
+	GELFCompressZlib
+)
+
+const (
+	// Default maximum size of a UDP datagram, fits a typical WAN MTU.
+	defaultGELFChunkSize = 1420
+	// Graylog discards messages split into more chunks.
+	maxGELFChunks = 128
+	// Chunk header: magic bytes, message id, sequence number and count.
+	gelfChunkHeaderLen = 12
+)
+
+// Characters allowed in GELF additional field names.
+var gelfFieldNameRe = regexp.MustCompile(`[^\w.\-]`)
+
+// GELFSink sends entries to Graylog using the GELF 1.1 format, either as
+// (optionally compressed and chunked) UDP datagrams or as null byte
+// delimited messages over TCP.
+type GELFSink struct {
+	sync.Mutex
+	network     string
+	addr        string
+	conn        net.Conn
+	compression GELFCompression
+	chunkSize   int
+}
+
+// NewGELFSink creates a sink for the GELF input at addr, given as
+// "udp://host:port" or "tcp://host:port".
+func NewGELFSink(addr string) (*GELFSink, error) {
+	network, destination, err := parseAddress(addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GELF address: %v", err)
+	}
+	if network != "udp" && network != "tcp" {
+		return nil, fmt.Errorf("invalid network type for GELF: %q", network)
+	}
+	conn, err := net.Dial(network, destination)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to GELF input: %v", err)
+	}
+	return &GELFSink{
+		network:   network,
+		addr:      destination,
+		conn:      conn,
+		chunkSize: defaultGELFChunkSize,
+	}, nil
+}
+
+// SetCompression sets the compression used for UDP messages.
+// Graylog does not support compression over TCP, so it is ignored there.
+func (s *GELFSink) SetCompression(c GELFCompression) {
+	s.Lock()
+	defer s.Unlock()
+	s.compression = c
+}
+
+// SetChunkSize sets the maximum UDP datagram size, messages that
+// are larger are split into GELF chunks.
+func (s *GELFSink) SetChunkSize(size int) error {
+	if size <= gelfChunkHeaderLen {
+		return fmt.Errorf("GELF chunk size must be larger than %d", gelfChunkHeaderLen)
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.chunkSize = size
+	return nil
+}
+
+// gelfLevel maps a level to the syslog severity GELF expects.
+func gelfLevel(lvl Level) int {
+	switch lvl {
+	case LevelFatal:
+		return 2
+	case LevelError:
+		return 3
+	case LevelWarn:
+		return 4
+	case LevelInfo:
+		return 6
+	}
+	return 7
+}
+
+// encodeGELF builds the GELF JSON document for an entry.
+func encodeGELF(e *Entry) ([]byte, error) {
+	msg := map[string]any{
+		"version":     "1.1",
+		"host":        e.Host,
+		"timestamp":   float64(e.Time.UnixMilli()) / 1000,
+		"level":       gelfLevel(e.Level),
+		"_pid":        e.PID,
+		"_level_name": e.Level.String(),
+	}
+	if short, _, multi := strings.Cut(e.Message, "\n"); multi {
+		msg["short_message"] = short
+		msg["full_message"] = e.Message
+	} else {
+		msg["short_message"] = e.Message
+	}
+	if msg["host"] == "" {
+		msg["host"] = "unknown"
+	}
+	for k, v := range e.Fields {
+		name := "_" + gelfFieldNameRe.ReplaceAllString(k, "_")
+		// "_id" is reserved by Graylog.
+		if name == "_id" {
+			name = "_id_"
+		}
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		msg[name] = v
+	}
+	return json.Marshal(msg)
+}
+
+func (s *GELFSink) compress(data []byte) ([]byte, error) {
+	var (
+		buf bytes.Buffer
+		w   interface {
+			Write([]byte) (int, error)
+			Close() error
+		}
+	)
+	switch s.compression {
+	case GELFCompressGzip:
+		w = gzip.NewWriter(&buf)
+	case GELFCompressZlib:
+		w = zlib.NewWriter(&buf)
+	default:
+		return data, nil
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeChunked sends data as a single datagram or as GELF chunks.
+func (s *GELFSink) writeChunked(data []byte) error {
+	if len(data) <= s.chunkSize {
+		_, err := s.conn.Write(data)
+		return err
+	}
+
+	payload := s.chunkSize - gelfChunkHeaderLen
+	count := (len(data) + payload - 1) / payload
+	if count > maxGELFChunks {
+		return fmt.Errorf("GELF message of %d bytes needs %d chunks, exceeds limit of %d", len(data), count, maxGELFChunks)
+	}
+
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return fmt.Errorf("unable to generate GELF message id: %w", err)
+	}
+	chunk := make([]byte, 0, s.chunkSize)
+	for seq := 0; seq < count; seq++ {
+		end := min((seq+1)*payload, len(data))
+		chunk = append(chunk[:0], 0x1e, 0x0f)
+		chunk = append(chunk, id[:]...)
+		chunk = append(chunk, byte(seq), byte(count))
+		chunk = append(chunk, data[seq*payload:end]...)
+		if _, err := s.conn.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeStream sends a null byte delimited message, reconnecting once
+// if the connection was lost.
+func (s *GELFSink) writeStream(data []byte) error {
+	data = append(data, 0)
+	_, err := s.conn.Write(data)
+	if err == nil {
+		return nil
+	}
+	s.conn.Close()
+	conn, derr := net.Dial(s.network, s.addr)
+	if derr != nil {
+		return fmt.Errorf("error writing to GELF input: %v, reconnect failed: %w", err, derr)
+	}
+	s.conn = conn
+	_, err = s.conn.Write(data)
+	return err
+}
+
+// WriteEntry implements the Sink interface.
+func (s *GELFSink) WriteEntry(e *Entry) error {
+	data, err := encodeGELF(e)
+	if err != nil {
+		return fmt.Errorf("unable to encode GELF message: %w", err)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if s.network == "tcp" {
+		return s.writeStream(data)
+	}
+	if data, err = s.compress(data); err != nil {
+		return fmt.Errorf("unable to compress GELF message: %w", err)
+	}
+	return s.writeChunked(data)
+}
+
+// Close closes the connection to the GELF input.
+func (s *GELFSink) Close() error {
+	s.Lock()
+	defer s.Unlock()
+	return s.conn.Close()
+}
@@ -0,0 +1,187 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Helper to receive a single GELF UDP message, reassembling chunks
+func readGELFUDP(t *testing.T, conn net.PacketConn) []byte {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 65536)
+	var chunks [][]byte
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("unable to read GELF message: %v", err)
+		}
+		data := append([]byte(nil), buf[:n]...)
+		if len(data) < 2 || data[0] != 0x1e || data[1] != 0x0f {
+			return data
+		}
+		seq, count := int(data[10]), int(data[11])
+		if chunks == nil {
+			chunks = make([][]byte, count)
+		}
+		chunks[seq] = data[12:]
+		if seq == count-1 {
+			return bytes.Join(chunks, nil)
+		}
+	}
+}
+
+func decodeGELF(t *testing.T, data []byte) map[string]any {
+	t.Helper()
+	var r io.Reader = bytes.NewReader(data)
+	switch {
+	case len(data) > 1 && data[0] == 0x1f && data[1] == 0x8b:
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			t.Fatalf("unable to read gzip: %v", err)
+		}
+		r = gz
+	case len(data) > 0 && data[0] == 0x78:
+		zr, err := zlib.NewReader(r)
+		if err != nil {
+			t.Fatalf("unable to read zlib: %v", err)
+		}
+		r = zr
+	}
+	var msg map[string]any
+	if err := json.NewDecoder(r).Decode(&msg); err != nil {
+		t.Fatalf("unable to decode GELF message: %v", err)
+	}
+	return msg
+}
+
+func newTestGELFUDP(t *testing.T) (*GELFSink, net.PacketConn) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	s, err := NewGELFSink("udp://" + conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Failed to create GELF sink: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s, conn
+}
+
+func TestGELFSink_UDP(t *testing.T) {
+	s, conn := newTestGELFUDP(t)
+	l := NewSinkLogger(false, false, s)
+
+	l.WithFields(Fields{"user": "bob", "id": 7, "bad key": true}).Errorf("first line\nsecond line")
+	msg := decodeGELF(t, readGELFUDP(t, conn))
+	if msg["version"] != "1.1" || msg["level"] != float64(3) || msg["_level_name"] != "error" {
+		t.Errorf("unexpected GELF header: %v", msg)
+	}
+	if msg["short_message"] != "first line" || msg["full_message"] != "first line\nsecond line" {
+		t.Errorf("unexpected GELF message: %v", msg)
+	}
+	if msg["_user"] != "bob" || msg["_id_"] != float64(7) || msg["_bad_key"] != true {
+		t.Errorf("unexpected GELF fields: %v", msg)
+	}
+	if msg["_pid"] == nil || msg["host"] == "" || msg["timestamp"] == nil {
+		t.Errorf("expected pid, host and timestamp: %v", msg)
+	}
+}
+
+func TestGELFSink_CompressedChunks(t *testing.T) {
+	for _, c := range []GELFCompression{GELFCompressNone, GELFCompressGzip, GELFCompressZlib} {
+		s, conn := newTestGELFUDP(t)
+		s.SetCompression(c)
+		if err := s.SetChunkSize(100); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		l := NewSinkLogger(false, false, s)
+		// Random looking data so that compression does not shrink it below a chunk.
+		var sb strings.Builder
+		for i := 0; i < 200; i++ {
+			sb.WriteString(time.Duration(i * 7919).String())
+		}
+		l.Warnf("%s", sb.String())
+		msg := decodeGELF(t, readGELFUDP(t, conn))
+		if msg["short_message"] != sb.String() {
+			t.Errorf("compression %d: unexpected message %v", c, msg["short_message"])
+		}
+	}
+}
+
+func TestGELFSink_TooManyChunks(t *testing.T) {
+	s, _ := newTestGELFUDP(t)
+	s.SetChunkSize(13)
+	if err := s.WriteEntry(&Entry{Message: strings.Repeat("x", 1000)}); err == nil {
+		t.Fatal("Expected error for message exceeding the chunk limit")
+	}
+	if err := s.SetChunkSize(12); err == nil {
+		t.Fatal("Expected error for chunk size not larger than the header")
+	}
+}
+
+func TestGELFSink_TCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	defer ln.Close()
+	msgs := make(chan []byte, 10)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		r := bufio.NewReader(c)
+		for {
+			b, err := r.ReadBytes(0)
+			if err != nil {
+				return
+			}
+			msgs <- b[:len(b)-1]
+		}
+	}()
+
+	s, err := NewGELFSink("tcp://" + ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to create GELF sink: %v", err)
+	}
+	s.SetCompression(GELFCompressGzip)
+	l := NewStdLogger(false, false, false, false, false)
+	l.logger.SetOutput(io.Discard)
+	l.AddSink(s)
+	defer l.Close()
+
+	l.Noticef("over tcp")
+	l.Warnf("again")
+	for _, expected := range []string{"over tcp", "again"} {
+		select {
+		case b := <-msgs:
+			if msg := decodeGELF(t, b); msg["short_message"] != expected {
+				t.Errorf("expected %q, got %v", expected, msg)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %q", expected)
+		}
+	}
+}
+
+func TestNewGELFSink_InvalidAddress(t *testing.T) {
+	for _, addr := range []string{"invalid://address", "unix:///tmp/gelf.sock"} {
+		if _, err := NewGELFSink(addr); err == nil {
+			t.Errorf("Expected error for address %q, got nil", addr)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrDropEntry is returned by a hook to veto an entry, which is then
+// neither written nor passed to the following hooks.
+var ErrDropEntry = errors.New("entry dropped by hook")
+
+// Hook is called for every entry of the levels it returns, or of all
+// levels when Levels returns none. Fire may observe the entry, enrich
+// it by changing its message or fields, or veto it with ErrDropEntry.
+type Hook interface {
+	Levels() []Level
+	Fire(e *Entry) error
+}
+
+// AddHook registers a hook, hooks run in the order they were added
+// before the entry is written. Hook errors and panics are reported to
+// the logger's error handler and do not prevent the entry from being
+// written.
+func (l *Logger) AddHook(h Hook) {
+	l.Lock()
+	defer l.Unlock()
+	l.hooks = append(l.hooks, h)
+}
+
+// runHooks fires the hooks matching the entry's level, it returns false
+// when the entry was vetoed.
+func (l *Logger) runHooks(hooks []Hook, e *Entry) bool {
+	for _, h := range hooks {
+		if !hookFiresAt(h, e.Level) {
+			continue
+		}
+		err := fireHook(h, e)
+		if errors.Is(err, ErrDropEntry) {
+			return false
+		}
+		if err != nil {
+			l.reportError(ErrorSourceHook, fmt.Errorf("%T: %w", h, err))
+		}
+	}
+	return true
+}
+
+func hookFiresAt(h Hook, level Level) bool {
+	levels := h.Levels()
+	if len(levels) == 0 {
+		return true
+	}
+	for _, lvl := range levels {
+		if lvl == level {
+			return true
+		}
+	}
+	return false
+}
+
+// fireHook calls the hook, turning a panic into an error.
+func fireHook(h Hook, e *Entry) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return h.Fire(e)
+}
@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Helper hook calling a function for the given levels.
+type testHook struct {
+	levels []Level
+	fire   func(e *Entry) error
+}
+
+func (h *testHook) Levels() []Level     { return h.levels }
+func (h *testHook) Fire(e *Entry) error { return h.fire(e) }
+
+func TestLoggerHooks(t *testing.T) {
+	l := newTestStdLogger(false, false, false, false, false)
+	var buf bytes.Buffer
+	l.logger.SetOutput(&buf)
+	sink := &testSink{}
+	l.AddSink(sink)
+
+	var order []string
+	l.AddHook(&testHook{fire: func(e *Entry) error {
+		order = append(order, "first")
+		e.Fields["request_id"] = "r1"
+		return nil
+	}})
+	l.AddHook(&testHook{levels: []Level{LevelError}, fire: func(e *Entry) error {
+		order = append(order, "errors only")
+		e.Message = "[alert] " + e.Message
+		return nil
+	}})
+	l.AddHook(&testHook{fire: func(e *Entry) error {
+		order = append(order, "veto")
+		if strings.Contains(e.Message, "noisy") {
+			return ErrDropEntry
+		}
+		return nil
+	}})
+
+	l.Noticef("hello")
+	l.Errorf("failure")
+	l.Warnf("noisy warning")
+
+	if got := strings.Join(order, ","); got != "first,veto,first,errors only,veto,first,veto" {
+		t.Errorf("Unexpected hook calls: %s", got)
+	}
+	entries := sink.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("Expected vetoed entry to be dropped, got %d entries", len(entries))
+	}
+	if entries[0].Fields["request_id"] != "r1" || entries[1].Message != "[alert] failure" {
+		t.Errorf("Expected hooks to enrich entries, got %+v %+v", entries[0], entries[1])
+	}
+	if out := buf.String(); strings.Contains(out, "noisy") || !strings.Contains(out, "[ERR] [alert] failure") {
+		t.Errorf("Unexpected text output:\n%s", out)
+	}
+}
+
+func TestLoggerHooks_DoNotModifyLoggerFields(t *testing.T) {
+	sink := &testSink{}
+	l := NewSinkLogger(false, false, sink).WithFields(Fields{"app": "x"})
+	l.AddHook(&testHook{fire: func(e *Entry) error {
+		e.Fields["added"] = true
+		return nil
+	}})
+	l.Noticef("one")
+	if _, ok := l.fields["added"]; ok {
+		t.Error("Expected hooks to get a copy of the logger fields")
+	}
+}
+
+func TestLoggerHooks_ErrorsAndPanics(t *testing.T) {
+	sink := &testSink{}
+	l := NewSinkLogger(false, false, sink)
+	l.SetRedactor(NewRedactor())
+	l.AddHook(&testHook{fire: func(e *Entry) error {
+		panic("boom")
+	}})
+	l.AddHook(&testHook{fire: func(e *Entry) error {
+		return errors.New("hook failed")
+	}})
+	l.AddHook(&testHook{fire: func(e *Entry) error {
+		e.Fields["token"] = "abc"
+		return nil
+	}})
+
+	l.Noticef("still written")
+	entries := sink.Entries()
+	if len(entries) != 1 || entries[0].Message != "still written" {
+		t.Fatalf("Expected entry despite hook failures, got %v", entries)
+	}
+	if entries[0].Fields["token"] != RedactedMask {
+		t.Errorf("Expected fields added by hooks to be redacted, got %v", entries[0].Fields)
+	}
+}
@@ -0,0 +1,355 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHTTPBatchSize  = 500
+	defaultHTTPBatchAge   = time.Second
+	defaultHTTPMaxQueue   = 10000
+	defaultHTTPMaxRetries = 5
+	defaultHTTPTimeout    = 10 * time.Second
+	minHTTPRetryDelay     = 100 * time.Millisecond
+	maxHTTPRetryDelay     = 30 * time.Second
+)
+
+// HTTPEncoder turns a batch of entries into a request body.
+type HTTPEncoder interface {
+	Encode(entries []*Entry) (body []byte, contentType string, err error)
+}
+
+// HTTPResponseHandler can be implemented by encoders whose endpoints
+// report per entry failures in successful responses. It returns the
+// entries that should be sent again and an error describing entries
+// that were rejected for good.
+type HTTPResponseHandler interface {
+	HandleResponse(batch []*Entry, body []byte) (retry []*Entry, err error)
+}
+
+// httpStatusError is returned for requests rejected by the server.
+type httpStatusError struct {
+	code       int
+	body       string
+	retryAfter time.Duration
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("server returned %d: %s", e.code, e.body)
+}
+
+// retryable reports whether the request may succeed if sent again.
+func (e *httpStatusError) retryable() bool {
+	return e.code == http.StatusTooManyRequests || e.code >= 500
+}
+
+// HTTPSink queues entries and POSTs them in batches from a
+// background goroutine, using an HTTPEncoder to build request bodies.
+// The queue is bounded, entries logged while it is full are dropped.
+// Failed requests are retried with exponential backoff when the server
+// asks for it (429 and 5xx responses) or the connection fails.
+type HTTPSink struct {
+	sync.Mutex
+	url        string
+	client     *http.Client
+	encoder    HTTPEncoder
+	header     http.Header
+	gzip       bool
+	queue      []*Entry
+	batchSize  int
+	batchAge   time.Duration
+	maxQueue   int
+	maxRetries int
+	kick       chan struct{}
+	quit       chan struct{}
+	done       chan struct{}
+	closed     bool
+	// flushMu serializes drains, so that Flush waits for a batch the
+	// background sender is posting.
+	flushMu sync.Mutex
+}
+
+// NewHTTPSink creates a sink posting batches encoded by encoder to the
+// given http or https URL.
+func NewHTTPSink(endpoint string, encoder HTTPEncoder) (*HTTPSink, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid URL: %q", endpoint)
+	}
+	s := &HTTPSink{
+		url:        endpoint,
+		client:     &http.Client{Timeout: defaultHTTPTimeout},
+		encoder:    encoder,
+		header:     make(http.Header),
+		batchSize:  defaultHTTPBatchSize,
+		batchAge:   defaultHTTPBatchAge,
+		maxQueue:   defaultHTTPMaxQueue,
+		maxRetries: defaultHTTPMaxRetries,
+		kick:       make(chan struct{}, 1),
+		quit:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// SetBatchSize sets the number of entries sent in a single request.
+func (s *HTTPSink) SetBatchSize(n int) {
+	s.Lock()
+	defer s.Unlock()
+	s.batchSize = max(n, 1)
+}
+
+// SetBatchAge sets the maximum time entries are held before being sent.
+// A value that is not positive restores the default of one second.
+func (s *HTTPSink) SetBatchAge(d time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+	if d <= 0 {
+		d = defaultHTTPBatchAge
+	}
+	s.batchAge = d
+}
+
+// SetMaxQueue sets the number of entries buffered while waiting to be
+// sent, further entries are dropped.
+func (s *HTTPSink) SetMaxQueue(n int) {
+	s.Lock()
+	defer s.Unlock()
+	s.maxQueue = n
+}
+
+// SetMaxRetries sets how many times a failed request is retried.
+func (s *HTTPSink) SetMaxRetries(n int) {
+	s.Lock()
+	defer s.Unlock()
+	s.maxRetries = n
+}
+
+// SetGzip enables gzip compression of request bodies.
+func (s *HTTPSink) SetGzip(enabled bool) {
+	s.Lock()
+	defer s.Unlock()
+	s.gzip = enabled
+}
+
+// SetHTTPClient replaces the client used to send requests.
+func (s *HTTPSink) SetHTTPClient(c *http.Client) {
+	s.Lock()
+	defer s.Unlock()
+	s.client = c
+}
+
+// SetTLSConfig sets the TLS configuration used for https endpoints,
+// e.g. to trust a private CA or present a client certificate.
+func (s *HTTPSink) SetTLSConfig(config *tls.Config) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+	s.Lock()
+	defer s.Unlock()
+	s.client = &http.Client{Timeout: s.client.Timeout, Transport: transport}
+}
+
+// SetHeader sets a header sent with every request, such as an
+// Authorization header carrying a token.
+func (s *HTTPSink) SetHeader(key, value string) {
+	s.Lock()
+	defer s.Unlock()
+	s.header.Set(key, value)
+}
+
+// SetBasicAuth sends HTTP basic authentication with every request.
+func (s *HTTPSink) SetBasicAuth(username, password string) {
+	auth := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	s.SetHeader("Authorization", "Basic "+auth)
+}
+
+// WriteEntry implements the Sink interface. Entries are queued and
+// sent asynchronously.
+func (s *HTTPSink) WriteEntry(e *Entry) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.closed {
+		return fmt.Errorf("sink is closed")
+	}
+	if len(s.queue) >= s.maxQueue {
+		return fmt.Errorf("queue full, entry dropped")
+	}
+	s.queue = append(s.queue, e)
+	if len(s.queue) >= s.batchSize {
+		select {
+		case s.kick <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (s *HTTPSink) run() {
+	defer close(s.done)
+	for {
+		s.Lock()
+		age := s.batchAge
+		s.Unlock()
+		timer := time.NewTimer(age)
+		select {
+		case <-s.kick:
+		case <-timer.C:
+		case <-s.quit:
+			timer.Stop()
+			return
+		}
+		timer.Stop()
+		if err := s.drain(true); err != nil {
+			countWriteError(ErrorSourceSink)
+			reportError(nil, ErrorSourceSink, fmt.Errorf("failed to send log entries to %s: %w", s.url, err))
+		}
+	}
+}
+
+// drain sends queued entries in batches until the queue is empty and
+// returns the last error encountered. Batches that fail are dropped.
+func (s *HTTPSink) drain(retry bool) error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+	var lastErr error
+	for {
+		s.Lock()
+		n := min(len(s.queue), s.batchSize)
+		if n == 0 {
+			s.Unlock()
+			return lastErr
+		}
+		batch := s.queue[:n:n]
+		s.queue = s.queue[n:]
+		maxRetries := s.maxRetries
+		s.Unlock()
+
+		if !retry {
+			maxRetries = 0
+		}
+		if err := s.post(batch, maxRetries); err != nil {
+			lastErr = fmt.Errorf("%d entries dropped: %w", len(batch), err)
+		}
+	}
+}
+
+// post encodes and sends a batch, retrying retryable failures. When the
+// encoder handles responses, only the entries it asks for are retried.
+func (s *HTTPSink) post(batch []*Entry, maxRetries int) error {
+	s.Lock()
+	client, compress := s.client, s.gzip
+	header := s.header.Clone()
+	s.Unlock()
+
+	var rejected error
+	delay := minHTTPRetryDelay
+	for attempt := 0; ; attempt++ {
+		body, contentType, err := s.encoder.Encode(batch)
+		if err != nil {
+			return fmt.Errorf("unable to encode batch: %w", err)
+		}
+		if compress {
+			var buf bytes.Buffer
+			zw := gzip.NewWriter(&buf)
+			zw.Write(body)
+			zw.Close()
+			body = buf.Bytes()
+		}
+		header.Set("Content-Type", contentType)
+		if compress {
+			header.Set("Content-Encoding", "gzip")
+		}
+
+		wait := delay
+		resp, err := s.do(client, body, header)
+		if err == nil {
+			h, ok := s.encoder.(HTTPResponseHandler)
+			if !ok {
+				return rejected
+			}
+			var retry []*Entry
+			retry, err = h.HandleResponse(batch, resp)
+			if err != nil {
+				rejected = err
+			}
+			if len(retry) == 0 {
+				return rejected
+			}
+			batch = retry
+			err = fmt.Errorf("%d entries were not accepted", len(retry))
+		} else if se, ok := err.(*httpStatusError); ok {
+			if !se.retryable() {
+				return errors.Join(rejected, err)
+			}
+			if se.retryAfter > 0 {
+				wait = min(se.retryAfter, maxHTTPRetryDelay)
+			}
+		}
+		if attempt >= maxRetries {
+			return errors.Join(rejected, err)
+		}
+		select {
+		case <-time.After(wait):
+		case <-s.quit:
+			return errors.Join(rejected, err)
+		}
+		delay = min(2*delay, maxHTTPRetryDelay)
+	}
+}
+
+// do sends a single request and returns the body of a successful response.
+func (s *HTTPSink) do(client *http.Client, body []byte, header http.Header) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return io.ReadAll(resp.Body)
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	se := &httpStatusError{code: resp.StatusCode, body: string(bytes.TrimSpace(msg))}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		se.retryAfter = time.Duration(secs) * time.Second
+	}
+	return nil, se
+}
+
+// Flush synchronously sends every queued entry, including a batch the
+// background sender is posting.
+func (s *HTTPSink) Flush() error {
+	return s.drain(true)
+}
+
+// Close stops the background sender and makes a final attempt to send
+// queued entries.
+func (s *HTTPSink) Close() error {
+	s.Lock()
+	if s.closed {
+		s.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.Unlock()
+
+	close(s.quit)
+	<-s.done
+	return s.drain(false)
+}
@@ -0,0 +1,262 @@
+//go:build linux
+
+package logger
+
+import (
+    "bytes"
+    "encoding/binary"
+    "errors"
+    "fmt"
+    "net"
+    "os"
+    "runtime"
+    "sort"
+    "strconv"
+    "strings"
+    "syscall"
+)
+
+// Default path of the systemd-journald native protocol socket.
+const defaultJournalSocket = "/run/systemd/journal/socket"
+
+// Journal priorities, matching the syslog severities.
+const (
+    journalPriErr     = 3
+    journalPriWarning = 4
+    journalPriNotice  = 5
+    journalPriDebug   = 7
+)
+
+// JournalLogger writes log entries to systemd-journald using the
+// native journal protocol, so that fields are queryable with journalctl.
+type JournalLogger struct {
+    conn       *net.UnixConn
+    addr       *net.UnixAddr
+    identifier string
+    fields     map[string]string
+    debug      bool
+    trace      bool
+}
+
+// NewJournalLogger creates a logger that sends entries to the journal
+// socket at addr. An empty addr selects the default journald socket.
+func NewJournalLogger(addr string, debug, trace bool) (*JournalLogger, error) {
+    if addr == "" {
+        addr = defaultJournalSocket
+    }
+    if _, err := os.Stat(addr); err != nil {
+        return nil, fmt.Errorf("failed to connect to journald: %v", err)
+    }
+    // The socket is left unconnected, passing file descriptors
+    // requires an explicit destination on every message.
+    conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+    if err != nil {
+        return nil, fmt.Errorf("failed to connect to journald: %v", err)
+    }
+    return &JournalLogger{
+        conn:       conn,
+        addr:       &net.UnixAddr{Name: addr, Net: "unixgram"},
+        identifier: GetSysLoggerTag(),
+        debug:      debug,
+        trace:      trace,
+    }, nil
+}
+
+// WithFields returns a logger that adds the given fields to every entry.
+// Keys are converted to valid journal field names (upper case letters,
+// digits and underscores). The returned logger shares the connection
+// with its parent.
+func (l *JournalLogger) WithFields(fields Fields) *JournalLogger {
+    nl := *l
+    nl.fields = make(map[string]string, len(l.fields)+len(fields))
+    for k, v := range l.fields {
+        nl.fields[k] = v
+    }
+    for k, v := range fields {
+        if name := journalFieldName(k); name != "" {
+            nl.fields[name] = fmt.Sprint(v)
+        }
+    }
+    return &nl
+}
+
+// Fields written by the logger itself, which custom fields must not repeat.
+var journalReservedFields = map[string]bool{
+    "MESSAGE":           true,
+    "PRIORITY":          true,
+    "SYSLOG_IDENTIFIER": true,
+    "SYSLOG_PID":        true,
+    "CODE_FILE":         true,
+    "CODE_LINE":         true,
+    "CODE_FUNC":         true,
+}
+
+// journalFieldName converts a key to a valid journal field name, or
+// returns an empty string if nothing usable is left. Names of fields
+// written by the logger get an F_ prefix.
+func journalFieldName(key string) string {
+    name := []byte(strings.ToUpper(key))
+    for i, c := range name {
+        if (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '_' {
+            name[i] = '_'
+        }
+    }
+    // Fields starting with an underscore are trusted fields set by journald.
+    s := strings.TrimLeft(string(name), "_")
+    if (s != "" && s[0] >= '0' && s[0] <= '9') || journalReservedFields[s] {
+        s = "F_" + s
+    }
+    if len(s) > 64 {
+        s = s[:64]
+    }
+    return s
+}
+
+// appendJournalField serializes a single field in the native protocol format.
+func appendJournalField(buf *bytes.Buffer, name, value string) {
+    if !strings.ContainsRune(value, '\n') {
+        buf.WriteString(name)
+        buf.WriteByte('=')
+        buf.WriteString(value)
+        buf.WriteByte('\n')
+        return
+    }
+    // Values containing newlines are sent as binary-safe, length prefixed data.
+    buf.WriteString(name)
+    buf.WriteByte('\n')
+    binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+    buf.WriteString(value)
+    buf.WriteByte('\n')
+}
+
+// encode builds the datagram for a single entry. A negative calldepth
+// omits the caller location.
+func (l *JournalLogger) encode(priority int, msg string, calldepth int, extra Fields) []byte {
+    var buf bytes.Buffer
+    appendJournalField(&buf, "MESSAGE", msg)
+    appendJournalField(&buf, "PRIORITY", strconv.Itoa(priority))
+    appendJournalField(&buf, "SYSLOG_IDENTIFIER", l.identifier)
+    appendJournalField(&buf, "SYSLOG_PID", strconv.Itoa(os.Getpid()))
+    if calldepth >= 0 {
+        if pc, file, line, ok := runtime.Caller(calldepth); ok {
+            appendJournalField(&buf, "CODE_FILE", file)
+            appendJournalField(&buf, "CODE_LINE", strconv.Itoa(line))
+            if fn := runtime.FuncForPC(pc); fn != nil {
+                appendJournalField(&buf, "CODE_FUNC", fn.Name())
+            }
+        }
+    }
+
+    fields := l.fields
+    if len(extra) > 0 {
+        fields = l.WithFields(extra).fields
+    }
+    // Sort custom fields so that the output is stable.
+    keys := make([]string, 0, len(fields))
+    for k := range fields {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+    for _, k := range keys {
+        appendJournalField(&buf, k, fields[k])
+    }
+    return buf.Bytes()
+}
+
+// send writes a datagram to the journal, falling back to passing the
+// descriptor of an anonymous memory file when the entry is too large
+// for a single datagram.
+func (l *JournalLogger) send(data []byte) error {
+    _, _, err := l.conn.WriteMsgUnix(data, nil, l.addr)
+    if err == nil {
+        return nil
+    }
+    if !errors.Is(err, syscall.EMSGSIZE) && !errors.Is(err, syscall.ENOBUFS) {
+        return err
+    }
+
+    f, err := journalMemFile()
+    if err != nil {
+        return fmt.Errorf("unable to create journal memory file: %w", err)
+    }
+    defer f.Close()
+    if _, err := f.Write(data); err != nil {
+        return fmt.Errorf("unable to write journal memory file: %w", err)
+    }
+
+    rights := syscall.UnixRights(int(f.Fd()))
+    _, _, err = l.conn.WriteMsgUnix(nil, rights, l.addr)
+    return err
+}
+
+// journalMemFile returns an unlinked file on the /dev/shm tmpfs to
+// hold entries that do not fit in a datagram.
+func journalMemFile() (*os.File, error) {
+    f, err := os.CreateTemp("/dev/shm", "journal.")
+    if err != nil {
+        return nil, err
+    }
+    os.Remove(f.Name())
+    return f, nil
+}
+
+func (l *JournalLogger) logf(priority int, format string, v ...interface{}) {
+    // Skip encode, logf and the level method to report the caller.
+    data := l.encode(priority, fmt.Sprintf(format, v...), 3, nil)
+    if err := l.send(data); err != nil {
+        countWriteError(ErrorSourceJournal)
+        reportError(nil, ErrorSourceJournal, fmt.Errorf("failed to write to journald: %w", err))
+    }
+}
+
+// Noticef logs a notice message.
+func (l *JournalLogger) Noticef(format string, v ...interface{}) {
+    l.logf(journalPriNotice, format, v...)
+}
+
+// Warnf logs a warning message.
+func (l *JournalLogger) Warnf(format string, v ...interface{}) {
+    l.logf(journalPriWarning, format, v...)
+}
+
+// Errorf logs an error message.
+func (l *JournalLogger) Errorf(format string, v ...interface{}) {
+    l.logf(journalPriErr, format, v...)
+}
+
+// Debugf logs a debug message if debug is enabled.
+func (l *JournalLogger) Debugf(format string, v ...interface{}) {
+    if l.debug {
+        l.logf(journalPriDebug, format, v...)
+    }
+}
+
+// Tracef logs a trace message if trace is enabled.
+func (l *JournalLogger) Tracef(format string, v ...interface{}) {
+    if l.trace {
+        l.logf(journalPriNotice, format, v...)
+    }
+}
+
+// WriteEntry implements the Sink interface so that a JournalLogger can
+// receive entries, including their fields, from a Logger.
+func (l *JournalLogger) WriteEntry(e *Entry) error {
+    priority := journalPriNotice
+    switch e.Level {
+    case LevelError, LevelFatal:
+        priority = journalPriErr
+    case LevelWarn:
+        priority = journalPriWarning
+    case LevelDebug:
+        priority = journalPriDebug
+    }
+    return l.send(l.encode(priority, e.Message, -1, e.Fields))
+}
+
+// Close closes the journal connection.
+func (l *JournalLogger) Close() error {
+    if l.conn != nil {
+        return l.conn.Close()
+    }
+    return nil
+}
@@ -0,0 +1,166 @@
+//go:build linux
+
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Helper to start a unixgram listener standing in for the journal socket
+func newTestJournal(t *testing.T) (string, *net.UnixConn) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return path, conn
+}
+
+// Helper to read and decode a single native protocol entry
+func readJournalEntry(t *testing.T, conn *net.UnixConn) map[string]string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 1<<16)
+	oob := make([]byte, 1024)
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		t.Fatalf("unable to read entry: %v", err)
+	}
+	data := buf[:n]
+	if n == 0 && oobn > 0 {
+		msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+		if err != nil || len(msgs) != 1 {
+			t.Fatalf("unable to parse control message: %v", err)
+		}
+		fds, err := syscall.ParseUnixRights(&msgs[0])
+		if err != nil || len(fds) != 1 {
+			t.Fatalf("unable to parse rights: %v", err)
+		}
+		f := os.NewFile(uintptr(fds[0]), "journal")
+		defer f.Close()
+		f.Seek(0, io.SeekStart)
+		if data, err = io.ReadAll(f); err != nil {
+			t.Fatalf("unable to read memory file: %v", err)
+		}
+	}
+
+	fields := map[string]string{}
+	for len(data) > 0 {
+		nl := bytes.IndexByte(data, '\n')
+		if nl < 0 {
+			t.Fatalf("malformed entry: %q", data)
+		}
+		line := string(data[:nl])
+		data = data[nl+1:]
+		if k, v, ok := strings.Cut(line, "="); ok {
+			fields[k] = v
+			continue
+		}
+		size := binary.LittleEndian.Uint64(data[:8])
+		fields[line] = string(data[8 : 8+size])
+		data = data[8+size+1:]
+	}
+	return fields
+}
+
+func TestJournalLogger(t *testing.T) {
+	path, conn := newTestJournal(t)
+
+	l, err := NewJournalLogger(path, true, false)
+	if err != nil {
+		t.Fatalf("Failed to create journal logger: %v", err)
+	}
+	defer l.Close()
+
+	l.WithFields(map[string]any{"request-id": 42, "_trusted": "x"}).Errorf("This is an %s log", "error")
+	f := readJournalEntry(t, conn)
+	if f["MESSAGE"] != "This is an error log" || f["PRIORITY"] != "3" {
+		t.Fatalf("unexpected entry: %v", f)
+	}
+	if !strings.HasSuffix(f["CODE_FILE"], "journald_test.go") || f["CODE_LINE"] == "" {
+		t.Fatalf("expected caller location, got %v", f)
+	}
+	if f["REQUEST_ID"] != "42" || f["TRUSTED"] != "x" {
+		t.Fatalf("expected custom fields, got %v", f)
+	}
+
+	l.Debugf("multi\nline")
+	f = readJournalEntry(t, conn)
+	if f["MESSAGE"] != "multi\nline" || f["PRIORITY"] != "7" {
+		t.Fatalf("unexpected entry: %v", f)
+	}
+	if _, ok := f["REQUEST_ID"]; ok {
+		t.Fatalf("fields should not leak to the parent logger: %v", f)
+	}
+}
+
+func TestJournalLogger_ReservedFields(t *testing.T) {
+	path, _ := newTestJournal(t)
+	l, err := NewJournalLogger(path, false, false)
+	if err != nil {
+		t.Fatalf("Failed to create journal logger: %v", err)
+	}
+	defer l.Close()
+
+	data := string(l.encode(6, "text", -1, Fields{"message": "user", "Priority": "high"}))
+	for _, name := range []string{"MESSAGE", "PRIORITY"} {
+		if n := strings.Count("\n"+data, "\n"+name+"="); n != 1 {
+			t.Errorf("Expected one %s field, got %d in %q", name, n, data)
+		}
+	}
+	if !strings.HasPrefix(data, "MESSAGE=text\n") {
+		t.Errorf("Expected the message to be kept, got %q", data)
+	}
+	if !strings.Contains(data, "F_MESSAGE=user\n") || !strings.Contains(data, "F_PRIORITY=high\n") {
+		t.Errorf("Expected prefixed custom fields, got %q", data)
+	}
+}
+
+func TestJournalLogger_LargeMessage(t *testing.T) {
+	if _, err := os.Stat("/dev/shm"); err != nil {
+		t.Skip("no /dev/shm available")
+	}
+	path, conn := newTestJournal(t)
+
+	l, err := NewJournalLogger(path, false, false)
+	if err != nil {
+		t.Fatalf("Failed to create journal logger: %v", err)
+	}
+	defer l.Close()
+
+	big := strings.Repeat("x", 4<<20)
+	l.Noticef("%s", big)
+	f := readJournalEntry(t, conn)
+	if f["MESSAGE"] != big {
+		t.Fatalf("expected large message of %d bytes, got %d", len(big), len(f["MESSAGE"]))
+	}
+}
+
+func TestJournalFieldName(t *testing.T) {
+	tests := map[string]string{
+		"user":       "USER",
+		"http.path":  "HTTP_PATH",
+		"__internal": "INTERNAL",
+		"1st":        "F_1ST",
+		"___":        "",
+		"message":    "F_MESSAGE",
+		"Priority":   "F_PRIORITY",
+		"code_line":  "F_CODE_LINE",
+	}
+	for key, expected := range tests {
+		if got := journalFieldName(key); got != expected {
+			t.Errorf("For key %q, expected %q, got %q", key, expected, got)
+		}
+	}
+}
@@ -1,18 +1,22 @@
 package logger
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Logger represents the server logger
 type Logger struct {
 	sync.Mutex
 	logger     *log.Logger
-	debug      bool
-	trace      bool
+	debug      atomic.Bool
+	trace      atomic.Bool
+	minLevel   atomic.Int32
 	infoLabel  string
 	warnLabel  string
 	errorLabel string
@@ -20,6 +24,15 @@ type Logger struct {
 	debugLabel string
 	traceLabel string
 	fl         *FileLogger
+	sinks      []Sink
+	fields     Fields
+	sampler    *sampler
+	dedup      *deduper
+	redactor   *Redactor
+	hooks      []Hook
+	onError    ErrorHandler
+	format     textFormat
+	clock      Clock
 }
 
 type LogOption interface {
@@ -31,35 +44,43 @@ type LogUTC bool
 
 func (l LogUTC) isLoggerOption() {}
 
-// logFlags returns the log flags based on the provided options.
-func logFlags(time bool, opts ...LogOption) int {
-	flags := 0
-	if time {
-		flags = log.LstdFlags | log.Lmicroseconds
-	}
+// LogFileMode sets the permissions of log files, including files created
+// by rotation. The default is 0640.
+type LogFileMode os.FileMode
 
-	for _, opt := range opts {
-		if v, ok := opt.(LogUTC); ok && time && bool(v) {
-			flags |= log.LUTC
-		}
-	}
+func (m LogFileMode) isLoggerOption() {}
 
-	return flags
+// LogFileOwner sets the owner of log files, including files created by
+// rotation, e.g. for daemons that drop privileges. An id of -1 is left
+// unchanged.
+type LogFileOwner struct {
+	UID int
+	GID int
 }
 
+func (o LogFileOwner) isLoggerOption() {}
+
+// LogDirMode creates missing parent directories of the log file with the
+// given permissions.
+type LogDirMode os.FileMode
+
+func (m LogDirMode) isLoggerOption() {}
+
 // NewStdLogger creates a standard logger that outputs to Stderr.
 func NewStdLogger(time, debug, trace, colors, pid bool, opts ...LogOption) *Logger {
-	flags := logFlags(time, opts...)
 	prefix := ""
 	if pid {
 		prefix = pidPrefix()
 	}
 
+	// Timestamps are written by the text format, not log.Logger.
 	l := &Logger{
-		logger: log.New(os.Stderr, prefix, flags),
-		debug:  debug,
-		trace:  trace,
+		logger: log.New(os.Stderr, prefix, 0),
+		format: newTextFormat(time, opts...),
+		clock:  clockOption(opts...),
 	}
+	l.debug.Store(debug)
+	l.trace.Store(trace)
 
 	if colors {
 		setColoredLabelFormats(l)
@@ -72,24 +93,25 @@ func NewStdLogger(time, debug, trace, colors, pid bool, opts ...LogOption) *Logg
 
 // NewFileLogger creates a file logger with output directed to the specified file.
 func NewFileLogger(filename string, time, debug, trace, pid bool, opts ...LogOption) *Logger {
-	flags := logFlags(time, opts...)
 	prefix := ""
 	if pid {
 		prefix = pidPrefix()
 	}
 
-	fl, err := newFileLogger(filename, prefix, time)
+	fl, err := newFileLogger(filename, prefix, time, opts...)
 	if err != nil {
 		log.Fatalf("error opening file: %v", err)
 		return nil
 	}
 
 	l := &Logger{
-		logger: log.New(fl, prefix, flags),
-		debug:  debug,
-		trace:  trace,
+		logger: log.New(fl, prefix, 0),
 		fl:     fl,
+		format: fl.format,
+		clock:  fl.clock,
 	}
+	l.debug.Store(debug)
+	l.trace.Store(trace)
 	fl.Lock()
 	fl.logger = l
 	fl.Unlock()
@@ -98,6 +120,88 @@ func NewFileLogger(filename string, time, debug, trace, pid bool, opts ...LogOpt
 	return l
 }
 
+// NewSinkLogger creates a logger without text output that only writes
+// structured entries to the given sinks.
+func NewSinkLogger(debug, trace bool, sinks ...Sink) *Logger {
+	l := &Logger{
+		sinks: sinks,
+	}
+	l.debug.Store(debug)
+	l.trace.Store(trace)
+	setPlainLabelFormats(l)
+	return l
+}
+
+// AddSink registers a sink that receives every entry logged
+// from now on, alongside the logger's existing output.
+func (l *Logger) AddSink(s Sink) {
+	l.Lock()
+	defer l.Unlock()
+	l.sinks = append(l.sinks, s)
+}
+
+// WithFields returns a logger that attaches the given fields to every
+// entry sent to sinks. The returned logger shares its output and sinks
+// with the parent, only one of them should be closed.
+func (l *Logger) WithFields(fields Fields) *Logger {
+	l.Lock()
+	defer l.Unlock()
+	nl := &Logger{
+		logger:     l.logger,
+		infoLabel:  l.infoLabel,
+		warnLabel:  l.warnLabel,
+		errorLabel: l.errorLabel,
+		fatalLabel: l.fatalLabel,
+		debugLabel: l.debugLabel,
+		traceLabel: l.traceLabel,
+		fl:         l.fl,
+		sinks:      l.sinks[:len(l.sinks):len(l.sinks)],
+		fields:     make(Fields, len(l.fields)+len(fields)),
+		sampler:    l.sampler,
+		dedup:      l.dedup,
+		redactor:   l.redactor,
+		hooks:      l.hooks[:len(l.hooks):len(l.hooks)],
+		onError:    l.onError,
+		format:     l.format,
+		clock:      l.clock,
+	}
+	nl.debug.Store(l.debug.Load())
+	nl.trace.Store(l.trace.Load())
+	nl.minLevel.Store(l.minLevel.Load())
+	for k, v := range l.fields {
+		nl.fields[k] = v
+	}
+	for k, v := range fields {
+		nl.fields[k] = v
+	}
+	return nl
+}
+
+// SetLevel sets the lowest level that is logged, enabling or disabling
+// debug and trace statements accordingly. Fatal statements are always
+// logged. Loggers derived with WithFields keep their own level.
+func (l *Logger) SetLevel(level Level) error {
+	if level < LevelTrace || level > LevelFatal {
+		return fmt.Errorf("invalid log level %d", level)
+	}
+	l.debug.Store(level <= LevelDebug)
+	l.trace.Store(level <= LevelTrace)
+	l.minLevel.Store(int32(level))
+	return nil
+}
+
+// Level returns the lowest level that is logged. With tracing enabled
+// but debug disabled, it is LevelTrace.
+func (l *Logger) Level() Level {
+	switch {
+	case l.trace.Load():
+		return LevelTrace
+	case l.debug.Load():
+		return LevelDebug
+	}
+	return max(Level(l.minLevel.Load()), LevelInfo)
+}
+
 // SetSizeLimit sets the size of a logfile after which a backup
 // is created with the file name + "year.month.day.hour.min.sec.nanosec"
 // and the current log is truncated.
@@ -126,14 +230,58 @@ func (l *Logger) SetMaxNumFiles(max int) error {
     return nil
 }
 
+// Rotate renames the log file to a timestamped backup and continues in
+// a new file, whether or not a size limit is set. Old backups are purged
+// according to SetMaxNumFiles. It returns the path of the backup.
+func (l *Logger) Rotate() (string, error) {
+    l.Lock()
+    if l.fl == nil {
+        l.Unlock()
+        return "", fmt.Errorf("can rotate only for file logger")
+    }
+    fl := l.fl
+    l.Unlock()
+    return fl.forceRotate()
+}
+
+// Reopen closes and reopens the log file, for use after it was moved
+// by an external tool such as logrotate.
+func (l *Logger) Reopen() error {
+    l.Lock()
+    if l.fl == nil {
+        l.Unlock()
+        return fmt.Errorf("can reopen only for file logger")
+    }
+    fl := l.fl
+    l.Unlock()
+    return fl.reopen()
+}
+
 // Close implements the io.Closer interface to clean up
 // resources in the server's logger implementation.
 // Caller must ensure threadsafety.
 func (l *Logger) Close() error {
+    if l.dedup != nil {
+        l.dedup.flush()
+    }
+    if l.sampler != nil {
+        l.sampler.stop()
+    }
+    var errs []error
+    for _, s := range l.sinks {
+        if err := s.Close(); err != nil {
+            errs = append(errs, err)
+        }
+    }
     if l.fl != nil {
-        return l.fl.close()
+        if err := l.fl.close(); err != nil {
+            errs = append(errs, err)
+        }
+        if cb := l.fl.callbacks; cb != nil {
+            cb.stop()
+        }
     }
-    return nil
+    return errors.Join(errs...)
 }
 
 // Generate the pid prefix string
@@ -160,36 +308,223 @@ func setColoredLabelFormats(l *Logger) {
 	l.traceLabel = fmt.Sprintf(colorFormat, "33", "TRC")
 }
 
+// logf applies sampling and deduplication, formats the message once and
+// sends it to the text output and to every registered sink.
+func (l *Logger) logf(level Level, label, format string, v ...any) {
+	if level < Level(l.minLevel.Load()) && level != LevelFatal {
+		countSuppressed(level, suppressGated)
+		return
+	}
+	l.Lock()
+	smp, dd := l.sampler, l.dedup
+	l.Unlock()
+	if smp != nil && !smp.allow(level, format) {
+		return
+	}
+	msg := fmt.Sprintf(format, maskArgs(v)...)
+	if dd != nil && !dd.check(level, msg) {
+		countSuppressed(level, suppressDeduplicated)
+		return
+	}
+	l.output(level, label, msg, nil)
+}
+
+// output redacts a formatted message, runs the hooks and writes it to
+// the text output and the sinks. Extra fields are added to the logger's
+// fields for this entry only.
+func (l *Logger) output(level Level, label, msg string, extra Fields) {
+	l.Lock()
+	sinks, hooks, r := l.sinks, l.hooks, l.redactor
+	l.Unlock()
+	if r != nil {
+		msg = r.RedactString(msg)
+	}
+	if len(sinks) == 0 && len(hooks) == 0 {
+		l.writeText(level, label, msg)
+		countEntry(level)
+		return
+	}
+
+	e := &Entry{
+		Time:    l.now(),
+		Level:   level,
+		Message: msg,
+		Host:    localHostname(),
+		PID:     os.Getpid(),
+	}
+	switch {
+	case len(extra) > 0 || len(hooks) > 0:
+		// Hooks may modify the fields, they get their own copy.
+		e.Fields = make(Fields, len(l.fields)+len(extra))
+		for k, v := range l.fields {
+			e.Fields[k] = v
+		}
+		for k, v := range extra {
+			e.Fields[k] = v
+		}
+	case len(l.fields) > 0:
+		e.Fields = l.fields
+	}
+	if len(e.Fields) > 0 {
+		e.Fields = r.redactFields(e.Fields)
+	}
+	if len(hooks) > 0 {
+		if !l.runHooks(hooks, e) {
+			countSuppressed(level, suppressHook)
+			return
+		}
+		// Messages and fields changed by hooks are redacted too.
+		if r != nil {
+			e.Message = r.RedactString(e.Message)
+		}
+		if len(e.Fields) > 0 {
+			e.Fields = r.redactFields(e.Fields)
+		}
+	}
+
+	l.writeText(level, label, e.Message)
+	countEntry(level)
+	for _, s := range sinks {
+		if err := s.WriteEntry(e); err != nil {
+			countWriteError(ErrorSourceSink)
+			l.reportError(ErrorSourceSink, fmt.Errorf("failed to write to %T: %w", s, err))
+		}
+	}
+}
+
+// writeText writes a message to the text output, if any, as one or
+// more lines depending on the multi-line mode.
+func (l *Logger) writeText(level Level, label, msg string) {
+	if l.logger == nil {
+		return
+	}
+	header := string(l.format.appendTimestamp(nil, l.now())) + label
+	for _, line := range l.format.lines(msg) {
+		// Skip writeText, output, logf and the level method when
+		// reporting the caller.
+		if err := l.logger.Output(5, header+line+l.format.eol); err != nil {
+			// Failures of the log file are counted where it is written.
+			source := ErrorSourceFile
+			if l.fl == nil {
+				source = ErrorSourceOutput
+				countWriteError(source)
+			}
+			l.reportError(source, err)
+			break
+		}
+	}
+	if l.fl != nil {
+		l.fl.flushAfter(level)
+	}
+}
+
+// label returns the text label for a level.
+func (l *Logger) label(level Level) string {
+	switch level {
+	case LevelTrace:
+		return l.traceLabel
+	case LevelDebug:
+		return l.debugLabel
+	case LevelWarn:
+		return l.warnLabel
+	case LevelError:
+		return l.errorLabel
+	case LevelFatal:
+		return l.fatalLabel
+	default:
+		return l.infoLabel
+	}
+}
+
 // Noticef logs a notice statement
 func (l *Logger) Noticef(format string, v ...any) {
-	l.logger.Printf(l.infoLabel+format, v...)
+	l.logf(LevelInfo, l.infoLabel, format, v...)
 }
 
 // Warnf logs a notice statement
 func (l *Logger) Warnf(format string, v ...any) {
-	l.logger.Printf(l.warnLabel+format, v...)
+	l.logf(LevelWarn, l.warnLabel, format, v...)
 }
 
 // Errorf logs an error statement
 func (l *Logger) Errorf(format string, v ...any) {
-	l.logger.Printf(l.errorLabel+format, v...)
+	l.logf(LevelError, l.errorLabel, format, v...)
 }
 
+// fatalFlushTimeout bounds how long Fatalf waits for sinks to deliver
+// pending entries before exiting.
+const fatalFlushTimeout = 5 * time.Second
+
 // Fatalf logs a fatal error
 func (l *Logger) Fatalf(format string, v ...any) {
-	l.logger.Fatalf(l.fatalLabel+format, v...)
+	l.logf(LevelFatal, l.fatalLabel, format, v...)
+	l.flushBeforeExit(fatalFlushTimeout)
+	os.Exit(1)
+}
+
+// flushBeforeExit reports pending repeats and delivers pending sink
+// entries, waiting at most d for the sinks.
+func (l *Logger) flushBeforeExit(d time.Duration) {
+	l.Lock()
+	dd := l.dedup
+	l.Unlock()
+	if dd != nil {
+		dd.flush()
+	}
+	l.flushSinksTimeout(d)
+}
+
+// flushSinksTimeout is flushSinks giving up after d, so that retrying
+// sinks cannot hold up the exit.
+func (l *Logger) flushSinksTimeout(d time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		l.flushSinks()
+		close(done)
+	}()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		l.reportError(ErrorSourceSink, fmt.Errorf("flushing sinks timed out after %v", d))
+	}
+}
+
+// flusher is implemented by sinks that send entries asynchronously.
+type flusher interface {
+	Flush() error
+}
+
+// flushSinks makes a best effort to deliver pending entries, used
+// before exiting.
+func (l *Logger) flushSinks() {
+	l.Lock()
+	sinks := l.sinks
+	l.Unlock()
+	for _, s := range sinks {
+		if f, ok := s.(flusher); ok {
+			if err := f.Flush(); err != nil {
+				l.reportError(ErrorSourceSink, fmt.Errorf("failed to flush %T: %w", s, err))
+			}
+		}
+	}
 }
 
 // Debugf logs a debug statement
 func (l *Logger) Debugf(format string, v ...any) {
-	if l.debug {
-		l.logger.Printf(l.debugLabel+format, v...)
+	if l.debug.Load() {
+		l.logf(LevelDebug, l.debugLabel, format, v...)
+	} else {
+		countSuppressed(LevelDebug, suppressGated)
 	}
 }
 
 // Tracef logs a trace statement
 func (l *Logger) Tracef(format string, v ...any) {
-	if l.trace {
-		l.logger.Printf(l.traceLabel+format, v...)
+	if l.trace.Load() {
+		l.logf(LevelTrace, l.traceLabel, format, v...)
+	} else {
+		countSuppressed(LevelTrace, suppressGated)
 	}
 }
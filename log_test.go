@@ -3,7 +3,11 @@ package logger
 import (
 	"bytes"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // Helper function to create a new standard logger for testing
@@ -16,6 +20,33 @@ func newTestFileLogger(filename string, time, debug, trace, pid bool) *Logger {
 	return NewFileLogger(filename, time, debug, trace, pid)
 }
 
+// Helper sink that records the entries it receives
+type testSink struct {
+	sync.Mutex
+	entries []*Entry
+	closed  bool
+}
+
+func (s *testSink) WriteEntry(e *Entry) error {
+	s.Lock()
+	defer s.Unlock()
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+func (s *testSink) Close() error {
+	s.Lock()
+	defer s.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *testSink) Entries() []*Entry {
+	s.Lock()
+	defer s.Unlock()
+	return append([]*Entry(nil), s.entries...)
+}
+
 // Test NewStdLogger function
 func TestNewStdLogger(t *testing.T) {
 	l := newTestStdLogger(true, true, false, false, true)
@@ -129,6 +160,177 @@ func TestLoggerFileRotation(t *testing.T) {
 	}
 }
 
+// Test that a size limit below the current size rotates without deadlock
+func TestLoggerSetSizeLimitBelowSize(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "test_limit.log")
+	l := newTestFileLogger(tmpFile, false, false, false, false)
+	defer l.Close()
+	for i := 0; i < 10; i++ {
+		l.Noticef("Log message number %d", i)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		l.SetSizeLimit(100)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("SetSizeLimit below the current size deadlocked")
+	}
+
+	matches, _ := filepath.Glob(tmpFile + ".*")
+	if len(matches) != 1 {
+		t.Errorf("Expected the file to be rotated, got backups %v", matches)
+	}
+}
+
+// Test forced rotation and purging of old backups
+func TestLoggerRotate(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "test_rotate.log")
+	l := newTestFileLogger(tmpFile, false, false, false, false)
+	defer l.Close()
+	l.SetMaxNumFiles(2)
+
+	var backups []string
+	for i := 0; i < 3; i++ {
+		l.Noticef("Before rotation %d", i)
+		bak, err := l.Rotate()
+		if err != nil {
+			t.Fatalf("Failed to rotate without a size limit: %v", err)
+		}
+		backups = append(backups, bak)
+	}
+
+	content, err := os.ReadFile(backups[2])
+	if err != nil || !strings.Contains(string(content), "Before rotation 2") {
+		t.Errorf("Expected backup to hold the last entry, got %q (%v)", content, err)
+	}
+	if _, err := os.Stat(backups[0]); !os.IsNotExist(err) {
+		t.Errorf("Expected the oldest backup to be purged, got %v", err)
+	}
+	content, _ = os.ReadFile(tmpFile)
+	if !strings.Contains(string(content), "Rotated log, backup saved as") {
+		t.Errorf("Expected rotation notice in the new file, got %q", content)
+	}
+
+	if _, err := newTestStdLogger(false, false, false, false, false).Rotate(); err == nil {
+		t.Error("Expected error rotating a logger without file")
+	}
+}
+
+// Test that a failed rotation keeps the logger writing
+func TestLoggerRotateFailure(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "test_rotate_failure.log")
+	l := newTestFileLogger(tmpFile, false, false, false, false)
+	defer l.Close()
+
+	l.Noticef("Before removal")
+	if err := os.Remove(tmpFile); err != nil {
+		t.Fatalf("Failed to remove log file: %v", err)
+	}
+	if _, err := l.Rotate(); err == nil {
+		t.Fatal("Expected error rotating a removed log file")
+	}
+
+	l.Noticef("After failed rotation")
+	content, err := os.ReadFile(tmpFile)
+	if err != nil || !strings.Contains(string(content), "After failed rotation") {
+		t.Errorf("Expected the log file to be recreated and written, got %q (%v)", content, err)
+	}
+	if _, err := l.Rotate(); err != nil {
+		t.Errorf("Expected rotation to work again, got %v", err)
+	}
+}
+
+// Test that Reopen recovers a broken file handle
+func TestLoggerReopenClosedHandle(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "test_reopen.log")
+	l := newTestFileLogger(tmpFile, false, false, false, false)
+	defer l.Close()
+	l.SetErrorHandler(func(string, error) {})
+
+	l.fl.Lock()
+	l.fl.file.Close()
+	l.fl.Unlock()
+	if err := l.Reopen(); err != nil {
+		t.Fatalf("Expected Reopen to recover a closed handle, got %v", err)
+	}
+	l.Noticef("After reopen")
+	content, err := os.ReadFile(tmpFile)
+	if err != nil || !strings.Contains(string(content), "After reopen") {
+		t.Errorf("Expected the log file to be written after reopen, got %q (%v)", content, err)
+	}
+}
+
+// Test that concurrent writes without rotation are not lost to a reopen
+func TestLoggerConcurrentWriteReopen(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "test_concurrent.log")
+	l := newTestFileLogger(tmpFile, false, false, false, false)
+	defer l.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if _, err := l.fl.Write([]byte("entry\n")); err != nil {
+					t.Errorf("Unexpected write error: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		if err := l.Reopen(); err != nil {
+			t.Fatalf("Failed to reopen: %v", err)
+		}
+	}
+	wg.Wait()
+
+	content, err := os.ReadFile(tmpFile)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if n := strings.Count(string(content), "entry\n"); n != 200 {
+		t.Errorf("Expected 200 entries, got %d", n)
+	}
+}
+
+// Test file mode, owner and directory options across rotation
+func TestLoggerFileOptions(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "nested", "logs")
+	tmpFile := filepath.Join(dir, "test_options.log")
+	l := NewFileLogger(tmpFile, false, false, false, false,
+		LogFileMode(0600), LogDirMode(0700), LogFileOwner{UID: os.Getuid(), GID: os.Getgid()})
+	defer l.Close()
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatalf("Expected log directory to be created: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0700 {
+		t.Errorf("Expected directory mode 0700, got %o", perm)
+	}
+
+	l.Noticef("Before rotation")
+	bak, err := l.Rotate()
+	if err != nil {
+		t.Fatalf("Failed to rotate: %v", err)
+	}
+	for _, name := range []string{bak, tmpFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("Failed to stat %q: %v", name, err)
+		}
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Errorf("Expected mode 0600 for %q, got %o", name, perm)
+		}
+	}
+}
+
 // Test Fatal log level (should exit the program)
 func TestLoggerFatal(t *testing.T) {
 	tmpFile := "./test_fatal.log"
@@ -153,3 +355,78 @@ func TestLoggerFatal(t *testing.T) {
 		t.Errorf("expected 'Fatal' log output, got %s", buf.String())
 	}
 }
+
+// Test that entries reach registered sinks with their fields
+func TestLoggerSinks(t *testing.T) {
+	l := newTestStdLogger(true, true, false, false, true)
+	var buf bytes.Buffer
+	l.logger.SetOutput(&buf)
+
+	sink := &testSink{}
+	l.AddSink(sink)
+	l.Noticef("plain %d", 1)
+	l.WithFields(Fields{"user": "bob"}).Errorf("with fields")
+	l.Tracef("trace is disabled")
+
+	entries := sink.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Level != LevelInfo || entries[0].Message != "plain 1" || entries[0].Fields != nil {
+		t.Errorf("unexpected entry: %+v", entries[0])
+	}
+	if entries[1].Level != LevelError || entries[1].Fields["user"] != "bob" {
+		t.Errorf("unexpected entry: %+v", entries[1])
+	}
+	if entries[0].PID != os.Getpid() || entries[0].Time.IsZero() {
+		t.Errorf("expected pid and time to be set: %+v", entries[0])
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("[ERR] with fields")) {
+		t.Errorf("expected text output alongside sinks, got %s", buf.String())
+	}
+
+	if err := l.Close(); err != nil || !sink.closed {
+		t.Errorf("expected sink to be closed, got err=%v", err)
+	}
+}
+
+// Test a logger writing only to sinks
+func TestNewSinkLogger(t *testing.T) {
+	sink := &testSink{}
+	l := NewSinkLogger(true, false, sink)
+	l.Debugf("debug")
+	l.Warnf("warn")
+	entries := sink.Entries()
+	if len(entries) != 2 || entries[0].Level != LevelDebug || entries[1].Level != LevelWarn {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+}
+
+// Helper sink whose Flush blocks until released
+type blockingFlushSink struct {
+	testSink
+	release chan struct{}
+}
+
+func (s *blockingFlushSink) Flush() error {
+	<-s.release
+	return nil
+}
+
+// Test that flushing before exit gives up after a timeout
+func TestLoggerFlushSinksTimeout(t *testing.T) {
+	s := &blockingFlushSink{release: make(chan struct{})}
+	defer close(s.release)
+	l := NewSinkLogger(false, false, s)
+	var errs errorCollector
+	l.SetErrorHandler(errs.handle)
+
+	start := time.Now()
+	l.flushSinksTimeout(50 * time.Millisecond)
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Expected the flush to give up after the timeout, took %v", d)
+	}
+	if len(errs.Errors()) != 1 {
+		t.Errorf("Expected the timeout to be reported, got %v", errs.Errors())
+	}
+}
@@ -0,0 +1,101 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LokiSink pushes batches of entries to a Loki compatible push API
+// endpoint (e.g. "http://loki:3100/loki/api/v1/push"). Every entry is
+// labelled with the configured static labels and its level; fields are
+// appended to the log line in logfmt style, since labels should have a
+// low cardinality.
+type LokiSink struct {
+	*HTTPSink
+}
+
+type lokiEncoder struct {
+	labels map[string]string
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// NewLokiSink creates a sink pushing to the given URL. The static labels
+// are attached to every stream.
+func NewLokiSink(pushURL string, labels map[string]string) (*LokiSink, error) {
+	enc := &lokiEncoder{labels: make(map[string]string, len(labels))}
+	for k, v := range labels {
+		enc.labels[k] = v
+	}
+	s, err := NewHTTPSink(pushURL, enc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Loki sink: %w", err)
+	}
+	return &LokiSink{HTTPSink: s}, nil
+}
+
+// logfmtLine renders a message followed by its fields as key=value
+// pairs, quoting values when needed.
+func logfmtLine(msg string, fields Fields) string {
+	if len(fields) == 0 {
+		return msg
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString(msg)
+	for _, k := range keys {
+		v := fmt.Sprint(fields[k])
+		sb.WriteByte(' ')
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+			v = strconv.Quote(v)
+		}
+		sb.WriteString(v)
+	}
+	return sb.String()
+}
+
+// Encode implements HTTPEncoder.
+func (enc *lokiEncoder) Encode(entries []*Entry) ([]byte, string, error) {
+	// Group entries by level, the only label that varies.
+	streams := make(map[Level]*lokiStream)
+	var order []Level
+	for _, e := range entries {
+		st, ok := streams[e.Level]
+		if !ok {
+			labels := make(map[string]string, len(enc.labels)+1)
+			for k, v := range enc.labels {
+				labels[k] = v
+			}
+			labels["level"] = e.Level.String()
+			st = &lokiStream{Stream: labels}
+			streams[e.Level] = st
+			order = append(order, e.Level)
+		}
+		st.Values = append(st.Values, [2]string{
+			strconv.FormatInt(e.Time.UnixNano(), 10),
+			logfmtLine(e.Message, e.Fields),
+		})
+	}
+
+	push := struct {
+		Streams []*lokiStream `json:"streams"`
+	}{}
+	for _, lvl := range order {
+		push.Streams = append(push.Streams, streams[lvl])
+	}
+	body, err := json.Marshal(push)
+	return body, "application/json", err
+}
@@ -0,0 +1,214 @@
+package logger
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type lokiPush struct {
+	Streams []struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	} `json:"streams"`
+}
+
+// Helper push endpoint that records requests, failing the first
+// failures requests with the given status
+type testLokiServer struct {
+	sync.Mutex
+	*httptest.Server
+	pushes   []lokiPush
+	requests int
+	failures int
+	status   int
+	received chan struct{}
+}
+
+func newTestLokiServer(t *testing.T, failures, status int) *testLokiServer {
+	t.Helper()
+	srv := &testLokiServer{failures: failures, status: status, received: make(chan struct{}, 100)}
+	srv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		srv.Lock()
+		defer srv.Unlock()
+		srv.requests++
+		if srv.requests <= srv.failures {
+			w.WriteHeader(srv.status)
+			return
+		}
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Errorf("invalid gzip body: %v", err)
+				return
+			}
+			body = zr
+		}
+		var p lokiPush
+		if err := json.NewDecoder(body).Decode(&p); err != nil {
+			t.Errorf("invalid push body: %v", err)
+		}
+		srv.pushes = append(srv.pushes, p)
+		w.WriteHeader(http.StatusNoContent)
+		srv.received <- struct{}{}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (srv *testLokiServer) wait(t *testing.T) lokiPush {
+	t.Helper()
+	select {
+	case <-srv.received:
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for push")
+	}
+	srv.Lock()
+	defer srv.Unlock()
+	return srv.pushes[len(srv.pushes)-1]
+}
+
+func TestLokiSink(t *testing.T) {
+	srv := newTestLokiServer(t, 0, 0)
+	s, err := NewLokiSink(srv.URL+"/loki/api/v1/push", map[string]string{"app": "test"})
+	if err != nil {
+		t.Fatalf("Failed to create Loki sink: %v", err)
+	}
+	s.SetBatchSize(3)
+	s.SetBatchAge(time.Hour)
+	s.SetGzip(true)
+	l := NewSinkLogger(false, false, s)
+	defer l.Close()
+
+	before := time.Now()
+	l.Noticef("one")
+	l.WithFields(Fields{"user": "bob smith", "n": 1}).Errorf("two")
+	l.Noticef("three")
+
+	p := srv.wait(t)
+	if len(p.Streams) != 2 {
+		t.Fatalf("expected 2 streams, got %+v", p)
+	}
+	info, errs := p.Streams[0], p.Streams[1]
+	if info.Stream["app"] != "test" || info.Stream["level"] != "info" || len(info.Values) != 2 {
+		t.Errorf("unexpected info stream: %+v", info)
+	}
+	if errs.Stream["level"] != "error" || errs.Values[0][1] != `two n=1 user="bob smith"` {
+		t.Errorf("unexpected error stream: %+v", errs)
+	}
+	ts, err := strconv.ParseInt(info.Values[0][0], 10, 64)
+	if err != nil || ts < before.UnixNano() {
+		t.Errorf("expected nanosecond timestamp, got %q", info.Values[0][0])
+	}
+}
+
+func TestLokiSink_Retry(t *testing.T) {
+	srv := newTestLokiServer(t, 2, http.StatusTooManyRequests)
+	s, err := NewLokiSink(srv.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to create Loki sink: %v", err)
+	}
+	s.SetBatchAge(10 * time.Millisecond)
+	defer s.Close()
+
+	s.WriteEntry(&Entry{Time: time.Now(), Level: LevelWarn, Message: "retried"})
+	p := srv.wait(t)
+	if p.Streams[0].Values[0][1] != "retried" {
+		t.Errorf("unexpected push: %+v", p)
+	}
+	srv.Lock()
+	defer srv.Unlock()
+	if srv.requests != 3 {
+		t.Errorf("expected 3 requests, got %d", srv.requests)
+	}
+}
+
+func TestLokiSink_NoRetryOnClientError(t *testing.T) {
+	srv := newTestLokiServer(t, 1, http.StatusBadRequest)
+	s, err := NewLokiSink(srv.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to create Loki sink: %v", err)
+	}
+	s.SetBatchAge(time.Hour)
+	defer s.Close()
+
+	s.WriteEntry(&Entry{Time: time.Now(), Message: "rejected"})
+	if err := s.Flush(); err == nil {
+		t.Fatal("expected error for rejected batch")
+	}
+	srv.Lock()
+	defer srv.Unlock()
+	if srv.requests != 1 {
+		t.Errorf("expected a single request, got %d", srv.requests)
+	}
+}
+
+func TestLokiSink_BoundedQueue(t *testing.T) {
+	srv := newTestLokiServer(t, 0, 0)
+	s, err := NewLokiSink(srv.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to create Loki sink: %v", err)
+	}
+	s.SetBatchAge(time.Hour)
+	s.SetMaxQueue(1)
+
+	if err := s.WriteEntry(&Entry{Time: time.Now(), Message: "kept"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := s.WriteEntry(&Entry{Time: time.Now(), Message: "dropped"}); err == nil {
+		t.Fatal("Expected error when the queue is full")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Unexpected error on Close: %v", err)
+	}
+	if p := srv.wait(t); len(p.Streams[0].Values) != 1 {
+		t.Errorf("expected only the queued entry, got %+v", p)
+	}
+}
+
+func TestLokiSink_FlushWaitsForInFlightBatch(t *testing.T) {
+	var mu sync.Mutex
+	count := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		mu.Lock()
+		count++
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s, err := NewLokiSink(srv.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to create Loki sink: %v", err)
+	}
+	s.SetBatchSize(1)
+	l := NewSinkLogger(false, false, s)
+	defer l.Close()
+
+	l.Errorf("in flight")
+	time.Sleep(20 * time.Millisecond) // let the background sender take the batch
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if count != 1 {
+		t.Errorf("Expected Flush to wait for the in-flight batch, got %d deliveries", count)
+	}
+}
+
+func TestNewLokiSink_InvalidURL(t *testing.T) {
+	for _, u := range []string{"", "loki:3100", "ftp://loki/push"} {
+		if _, err := NewLokiSink(u, nil); err == nil {
+			t.Errorf("Expected error for URL %q", u)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package logger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+const numLevels = int(LevelFatal) + 1
+
+// Reasons for entries not being written.
+const (
+	suppressGated = iota
+	suppressSampled
+	suppressRateLimited
+	suppressDeduplicated
+	suppressHook
+	numSuppressReasons
+)
+
+var suppressReasons = [numSuppressReasons]string{"gated", "sampled", "rate_limited", "deduplicated", "hook"}
+
+// Destinations of log output whose write failures are counted.
+var writeSources = [...]string{ErrorSourceFile, ErrorSourceJournal, ErrorSourceOutput, ErrorSourceSink}
+
+// metrics holds the process wide counters of logging activity.
+var metrics struct {
+	entries        [numLevels]atomic.Uint64
+	suppressed     [numLevels][numSuppressReasons]atomic.Uint64
+	bytes          atomic.Uint64
+	rotations      atomic.Uint64
+	purges         atomic.Uint64
+	purgeFailures  atomic.Uint64
+	syslogFailures atomic.Uint64
+	writeErrors    [len(writeSources)]atomic.Uint64
+}
+
+func countEntry(level Level) {
+	if level >= 0 && int(level) < numLevels {
+		metrics.entries[level].Add(1)
+	}
+}
+
+func countSuppressed(level Level, reason int) {
+	if level >= 0 && int(level) < numLevels {
+		metrics.suppressed[level][reason].Add(1)
+	}
+}
+
+// countWriteError counts a failure to write log output to a destination.
+// Other errors reported under the same source, such as sync errors, are
+// not counted.
+func countWriteError(source string) {
+	for i, src := range writeSources {
+		if src == source {
+			metrics.writeErrors[i].Add(1)
+			return
+		}
+	}
+}
+
+func countBytes(n int) {
+	if n > 0 {
+		metrics.bytes.Add(uint64(n))
+	}
+}
+
+// MetricsHandler returns a handler serving the logging metrics of the
+// process in the Prometheus text exposition format.
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WriteMetrics(w)
+	})
+}
+
+// WriteMetrics writes the logging metrics of the process in the
+// Prometheus text exposition format: entries written and suppressed by
+// level, bytes written to log files, rotations, purges, and errors.
+func WriteMetrics(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	family := func(name, help string) {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+	}
+
+	family("logger_entries_total", "Log entries written, by level.")
+	for lvl := Level(0); int(lvl) < numLevels; lvl++ {
+		fmt.Fprintf(bw, "logger_entries_total{level=%q} %d\n", lvl.String(), metrics.entries[lvl].Load())
+	}
+	family("logger_entries_suppressed_total", "Log entries not written, by level and reason.")
+	for lvl := Level(0); int(lvl) < numLevels; lvl++ {
+		for reason, name := range suppressReasons {
+			fmt.Fprintf(bw, "logger_entries_suppressed_total{level=%q,reason=%q} %d\n",
+				lvl.String(), name, metrics.suppressed[lvl][reason].Load())
+		}
+	}
+	family("logger_file_bytes_written_total", "Bytes written to log files.")
+	fmt.Fprintf(bw, "logger_file_bytes_written_total %d\n", metrics.bytes.Load())
+	family("logger_file_rotations_total", "Log file rotations.")
+	fmt.Fprintf(bw, "logger_file_rotations_total %d\n", metrics.rotations.Load())
+	family("logger_file_purges_total", "Backup log files removed.")
+	fmt.Fprintf(bw, "logger_file_purges_total %d\n", metrics.purges.Load())
+	family("logger_file_purge_failures_total", "Failures to list or remove backup log files.")
+	fmt.Fprintf(bw, "logger_file_purge_failures_total %d\n", metrics.purgeFailures.Load())
+
+	family("logger_write_errors_total", "Failures to write log output, by destination.")
+	for i, src := range writeSources {
+		fmt.Fprintf(bw, "logger_write_errors_total{source=%q} %d\n", src, metrics.writeErrors[i].Load())
+	}
+	family("logger_syslog_send_failures_total", "Failures to send messages to syslog destinations.")
+	fmt.Fprintf(bw, "logger_syslog_send_failures_total %d\n", metrics.syslogFailures.Load())
+	family("logger_hook_errors_total", "Hook errors and panics.")
+	fmt.Fprintf(bw, "logger_hook_errors_total %d\n", ErrorCounts()[ErrorSourceHook])
+	return bw.Flush()
+}
@@ -0,0 +1,138 @@
+package logger
+
+import (
+	"bufio"
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Helper parsing the exposition format into sample values by series.
+func scrapeMetrics(t *testing.T) map[string]float64 {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type %q", ct)
+	}
+	samples := make(map[string]float64)
+	sc := bufio.NewScanner(rec.Body)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndexByte(line, ' ')
+		v, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			t.Fatalf("Invalid sample line %q", line)
+		}
+		samples[line[:i]] = v
+	}
+	return samples
+}
+
+func TestMetrics(t *testing.T) {
+	before := scrapeMetrics(t)
+	delta := func(after map[string]float64, series string) float64 {
+		if _, ok := after[series]; !ok {
+			t.Fatalf("Missing series %s", series)
+		}
+		return after[series] - before[series]
+	}
+
+	tmpDir := t.TempDir()
+	l := NewFileLogger(filepath.Join(tmpDir, "metrics.log"), false, false, false, false)
+	defer l.Close()
+	l.SetSizeLimit(100)
+	l.SetMaxNumFiles(1)
+	l.AddHook(&testHook{levels: []Level{LevelWarn}, fire: func(e *Entry) error { return ErrDropEntry }})
+
+	for i := 0; i < 5; i++ {
+		l.Noticef("entry number %d with some padding to rotate the file", i)
+	}
+	l.Errorf("an error")
+	l.Warnf("vetoed")
+	l.Debugf("gated")
+	l.Tracef("gated")
+	l.SetDedup(time.Second)
+	l.Errorf("again")
+	l.Errorf("again")
+
+	after := scrapeMetrics(t)
+	checks := map[string]float64{
+		`logger_entries_total{level="info"}`:                                   5,
+		`logger_entries_total{level="error"}`:                                  2,
+		`logger_entries_suppressed_total{level="warn",reason="hook"}`:          1,
+		`logger_entries_suppressed_total{level="debug",reason="gated"}`:        1,
+		`logger_entries_suppressed_total{level="trace",reason="gated"}`:        1,
+		`logger_entries_suppressed_total{level="error",reason="deduplicated"}`: 1,
+	}
+	for series, want := range checks {
+		if got := delta(after, series); got != want {
+			t.Errorf("%s increased by %v, want %v", series, got, want)
+		}
+	}
+	if delta(after, "logger_file_rotations_total") < 1 || delta(after, "logger_file_purges_total") < 1 {
+		t.Error("Expected rotations and purges to be counted")
+	}
+	if delta(after, "logger_file_bytes_written_total") < 300 {
+		t.Errorf("Expected bytes written to be counted, got %v", delta(after, "logger_file_bytes_written_total"))
+	}
+	for _, series := range []string{`logger_write_errors_total{source="file"}`, "logger_syslog_send_failures_total",
+		"logger_file_purge_failures_total", "logger_hook_errors_total"} {
+		delta(after, series)
+	}
+}
+
+// Helper syslog writer failing every message
+type failingSyslogWriter struct{}
+
+func (failingSyslogWriter) Notice(string) error  { return errors.New("connection refused") }
+func (failingSyslogWriter) Warning(string) error { return errors.New("connection refused") }
+func (failingSyslogWriter) Err(string) error     { return errors.New("connection refused") }
+func (failingSyslogWriter) Debug(string) error   { return errors.New("connection refused") }
+func (failingSyslogWriter) Close() error         { return nil }
+
+func TestMetrics_SyslogSendFailures(t *testing.T) {
+	sl := &SysLogger{writer: failingSyslogWriter{}}
+	l := NewSinkLogger(false, false, sl)
+	l.SetErrorHandler(func(string, error) {})
+	SetErrorHandler(func(string, error) {})
+	defer SetErrorHandler(nil)
+
+	before := scrapeMetrics(t)
+	sl.Noticef("direct")
+	l.Errorf("through a logger")
+	after := scrapeMetrics(t)
+	name := "logger_syslog_send_failures_total"
+	if got := after[name] - before[name]; got != 2 {
+		t.Errorf("%s increased by %v, want 2", name, got)
+	}
+}
+
+func TestMetrics_WriteErrors(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.log")
+	l := NewFileLogger(file, false, false, false, false)
+	defer l.Close()
+	l.SetErrorHandler(func(string, error) {})
+
+	series := `logger_write_errors_total{source="file"}`
+	before := scrapeMetrics(t)
+	l.fl.Lock()
+	l.fl.file = &failingFile{name: file}
+	l.fl.Unlock()
+	l.fl.logDirect(l.infoLabel, "direct")
+	l.Noticef("through the logger")
+	// Errors that are not write failures are not counted.
+	l.reportError(ErrorSourceFile, errors.New("sync failed"))
+
+	after := scrapeMetrics(t)
+	if got := after[series] - before[series]; got != 2 {
+		t.Errorf("%s increased by %v, want 2", series, got)
+	}
+}
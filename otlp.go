@@ -0,0 +1,410 @@
+package logger
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// OTLPEncoding selects the payload encoding of OTLP/HTTP requests.
+type OTLPEncoding int
+
+const (
+	OTLPProtobuf OTLPEncoding = iota
+	OTLPJSON
+)
+
+// Instrumentation scope reported for every exported record.
+const otlpScopeName = "github.com/ninepeach/logger"
+
+// Field names that carry trace context instead of attributes.
+const (
+	OTLPTraceIDField = "trace_id"
+	OTLPSpanIDField  = "span_id"
+)
+
+// OTLPSink exports entries as OpenTelemetry LogRecords to an OTLP/HTTP
+// endpoint (e.g. "http://collector:4318/v1/logs"). Entry fields become
+// record attributes, except for the trace_id and span_id fields which,
+// given as hex strings or byte slices, set the record's trace context.
+type OTLPSink struct {
+	*HTTPSink
+	enc *otlpEncoder
+}
+
+type otlpEncoder struct {
+	sync.Mutex
+	encoding OTLPEncoding
+	resource []otlpKeyValue
+}
+
+// NewOTLPSink creates an exporter for the given endpoint. The service
+// name, process id and host name are reported as resource attributes.
+func NewOTLPSink(endpoint, serviceName string, encoding OTLPEncoding) (*OTLPSink, error) {
+	if encoding != OTLPProtobuf && encoding != OTLPJSON {
+		return nil, fmt.Errorf("invalid OTLP encoding: %d", encoding)
+	}
+	enc := &otlpEncoder{encoding: encoding}
+	enc.setResource(nil, serviceName)
+	s, err := NewHTTPSink(endpoint, enc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP sink: %w", err)
+	}
+	return &OTLPSink{HTTPSink: s, enc: enc}, nil
+}
+
+// SetResourceAttributes adds attributes describing the resource, such
+// as service.version or deployment.environment, to the defaults.
+func (s *OTLPSink) SetResourceAttributes(attrs Fields) {
+	s.enc.Lock()
+	defer s.enc.Unlock()
+	var serviceName string
+	if sv := s.enc.resource[0].Value.StringValue; sv != nil {
+		serviceName = *sv
+	}
+	s.enc.setResource(attrs, serviceName)
+}
+
+// setResource rebuilds the resource attributes, service.name first.
+func (enc *otlpEncoder) setResource(attrs Fields, serviceName string) {
+	base := Fields{
+		"process.pid": os.Getpid(),
+		"host.name":   localHostname(),
+	}
+	for k, v := range attrs {
+		base[k] = v
+	}
+	delete(base, "service.name")
+	enc.resource = append([]otlpKeyValue{{Key: "service.name", Value: otlpValueOf(serviceName)}}, otlpAttributes(base)...)
+}
+
+// OTLP JSON representation, also used as the model for protobuf encoding.
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string     `json:"stringValue,omitempty"`
+	BoolValue   *bool       `json:"boolValue,omitempty"`
+	IntValue    *int64      `json:"intValue,omitempty,string"`
+	DoubleValue *float64    `json:"doubleValue,omitempty"`
+	ArrayValue  *otlpArray  `json:"arrayValue,omitempty"`
+	KvlistValue *otlpKvlist `json:"kvlistValue,omitempty"`
+	BytesValue  []byte      `json:"bytesValue,omitempty"`
+}
+
+type otlpArray struct {
+	Values []otlpAnyValue `json:"values"`
+}
+
+type otlpKvlist struct {
+	Values []otlpKeyValue `json:"values"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         uint64         `json:"timeUnixNano,string"`
+	ObservedTimeUnixNano uint64         `json:"observedTimeUnixNano,string"`
+	SeverityNumber       int            `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 otlpAnyValue   `json:"body"`
+	Attributes           []otlpKeyValue `json:"attributes,omitempty"`
+	TraceID              string         `json:"traceId,omitempty"`
+	SpanID               string         `json:"spanId,omitempty"`
+}
+
+// otlpSeverity maps a level to the OTLP severity number and text.
+func otlpSeverity(lvl Level) (int, string) {
+	switch lvl {
+	case LevelTrace:
+		return 1, "TRACE"
+	case LevelDebug:
+		return 5, "DEBUG"
+	case LevelInfo:
+		return 9, "INFO"
+	case LevelWarn:
+		return 13, "WARN"
+	case LevelError:
+		return 17, "ERROR"
+	case LevelFatal:
+		return 21, "FATAL"
+	}
+	return 0, ""
+}
+
+func otlpValueOf(v any) otlpAnyValue {
+	var av otlpAnyValue
+	switch v := v.(type) {
+	case string:
+		av.StringValue = &v
+	case bool:
+		av.BoolValue = &v
+	case int, int8, int16, int32, int64, uint8, uint16, uint32:
+		i := toInt64(v)
+		av.IntValue = &i
+	case uint, uint64:
+		i := toInt64(v)
+		av.IntValue = &i
+	case float32:
+		f := float64(v)
+		av.DoubleValue = &f
+	case float64:
+		av.DoubleValue = &v
+	case []byte:
+		av.BytesValue = v
+	case error:
+		s := v.Error()
+		av.StringValue = &s
+	case fmt.Stringer:
+		s := v.String()
+		av.StringValue = &s
+	case []any:
+		arr := &otlpArray{Values: make([]otlpAnyValue, 0, len(v))}
+		for _, e := range v {
+			arr.Values = append(arr.Values, otlpValueOf(e))
+		}
+		av.ArrayValue = arr
+	case map[string]any:
+		av.KvlistValue = &otlpKvlist{Values: otlpAttributes(v)}
+	case Fields:
+		av.KvlistValue = &otlpKvlist{Values: otlpAttributes(v)}
+	default:
+		s := fmt.Sprint(v)
+		av.StringValue = &s
+	}
+	return av
+}
+
+// toInt64 converts any integer type, saturating large unsigned values.
+func toInt64(v any) int64 {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case uint:
+		return int64(min(uint64(v), math.MaxInt64))
+	case uint64:
+		return int64(min(v, math.MaxInt64))
+	}
+	return 0
+}
+
+// otlpAttributes converts fields to key values sorted by key.
+func otlpAttributes(fields map[string]any) []otlpKeyValue {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	kvs := make([]otlpKeyValue, 0, len(keys))
+	for _, k := range keys {
+		kvs = append(kvs, otlpKeyValue{Key: k, Value: otlpValueOf(fields[k])})
+	}
+	return kvs
+}
+
+// otlpTraceContextID returns a hex encoded id of the given size from a
+// field value, or an empty string if the value is not a valid id.
+func otlpTraceContextID(v any, size int) string {
+	var id []byte
+	switch v := v.(type) {
+	case string:
+		b, err := hex.DecodeString(strings.TrimSpace(v))
+		if err != nil {
+			return ""
+		}
+		id = b
+	case []byte:
+		id = v
+	case [16]byte:
+		id = v[:]
+	case [8]byte:
+		id = v[:]
+	}
+	if len(id) != size {
+		return ""
+	}
+	return hex.EncodeToString(id)
+}
+
+func otlpRecord(e *Entry) otlpLogRecord {
+	num, text := otlpSeverity(e.Level)
+	rec := otlpLogRecord{
+		TimeUnixNano:         uint64(e.Time.UnixNano()),
+		ObservedTimeUnixNano: uint64(e.Time.UnixNano()),
+		SeverityNumber:       num,
+		SeverityText:         text,
+		Body:                 otlpValueOf(e.Message),
+	}
+	attrs := make(map[string]any, len(e.Fields))
+	for k, v := range e.Fields {
+		switch k {
+		case OTLPTraceIDField:
+			if id := otlpTraceContextID(v, 16); id != "" {
+				rec.TraceID = id
+				continue
+			}
+		case OTLPSpanIDField:
+			if id := otlpTraceContextID(v, 8); id != "" {
+				rec.SpanID = id
+				continue
+			}
+		}
+		attrs[k] = v
+	}
+	rec.Attributes = otlpAttributes(attrs)
+	return rec
+}
+
+// Encode implements HTTPEncoder.
+func (enc *otlpEncoder) Encode(entries []*Entry) ([]byte, string, error) {
+	records := make([]otlpLogRecord, 0, len(entries))
+	for _, e := range entries {
+		records = append(records, otlpRecord(e))
+	}
+	enc.Lock()
+	resource, encoding := enc.resource, enc.encoding
+	enc.Unlock()
+
+	if encoding == OTLPJSON {
+		req := map[string]any{
+			"resourceLogs": []any{map[string]any{
+				"resource": map[string]any{"attributes": resource},
+				"scopeLogs": []any{map[string]any{
+					"scope":      map[string]any{"name": otlpScopeName},
+					"logRecords": records,
+				}},
+			}},
+		}
+		body, err := json.Marshal(req)
+		return body, "application/json", err
+	}
+	return otlpProtoRequest(resource, records), "application/x-protobuf", nil
+}
+
+// Protobuf encoding of ExportLogsServiceRequest. Nested messages are
+// encoded first and then appended as length delimited fields.
+
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+)
+
+func appendProtoTag(b []byte, field, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field<<3|wireType))
+}
+
+func appendProtoBytes(b []byte, field int, v []byte) []byte {
+	b = appendProtoTag(b, field, protoBytes)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendProtoString(b []byte, field int, v string) []byte {
+	return appendProtoBytes(b, field, []byte(v))
+}
+
+func appendProtoVarint(b []byte, field int, v uint64) []byte {
+	b = appendProtoTag(b, field, protoVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+func appendProtoFixed64(b []byte, field int, v uint64) []byte {
+	b = appendProtoTag(b, field, protoFixed64)
+	return binary.LittleEndian.AppendUint64(b, v)
+}
+
+func protoAnyValue(v otlpAnyValue) []byte {
+	var b []byte
+	switch {
+	case v.StringValue != nil:
+		b = appendProtoString(b, 1, *v.StringValue)
+	case v.BoolValue != nil:
+		var x uint64
+		if *v.BoolValue {
+			x = 1
+		}
+		b = appendProtoVarint(b, 2, x)
+	case v.IntValue != nil:
+		b = appendProtoVarint(b, 3, uint64(*v.IntValue))
+	case v.DoubleValue != nil:
+		b = appendProtoFixed64(b, 4, math.Float64bits(*v.DoubleValue))
+	case v.ArrayValue != nil:
+		var arr []byte
+		for _, e := range v.ArrayValue.Values {
+			arr = appendProtoBytes(arr, 1, protoAnyValue(e))
+		}
+		b = appendProtoBytes(b, 5, arr)
+	case v.KvlistValue != nil:
+		var kvs []byte
+		for _, kv := range v.KvlistValue.Values {
+			kvs = appendProtoBytes(kvs, 1, protoKeyValue(kv))
+		}
+		b = appendProtoBytes(b, 6, kvs)
+	case v.BytesValue != nil:
+		b = appendProtoBytes(b, 7, v.BytesValue)
+	}
+	return b
+}
+
+func protoKeyValue(kv otlpKeyValue) []byte {
+	b := appendProtoString(nil, 1, kv.Key)
+	return appendProtoBytes(b, 2, protoAnyValue(kv.Value))
+}
+
+func protoLogRecord(r otlpLogRecord) []byte {
+	b := appendProtoFixed64(nil, 1, r.TimeUnixNano)
+	b = appendProtoVarint(b, 2, uint64(r.SeverityNumber))
+	b = appendProtoString(b, 3, r.SeverityText)
+	b = appendProtoBytes(b, 5, protoAnyValue(r.Body))
+	for _, kv := range r.Attributes {
+		b = appendProtoBytes(b, 6, protoKeyValue(kv))
+	}
+	if r.TraceID != "" {
+		id, _ := hex.DecodeString(r.TraceID)
+		b = appendProtoBytes(b, 9, id)
+	}
+	if r.SpanID != "" {
+		id, _ := hex.DecodeString(r.SpanID)
+		b = appendProtoBytes(b, 10, id)
+	}
+	return appendProtoFixed64(b, 11, r.ObservedTimeUnixNano)
+}
+
+func otlpProtoRequest(resource []otlpKeyValue, records []otlpLogRecord) []byte {
+	var res []byte
+	for _, kv := range resource {
+		res = appendProtoBytes(res, 1, protoKeyValue(kv))
+	}
+	scope := appendProtoString(nil, 1, otlpScopeName)
+
+	scopeLogs := appendProtoBytes(nil, 1, scope)
+	for _, r := range records {
+		scopeLogs = appendProtoBytes(scopeLogs, 2, protoLogRecord(r))
+	}
+
+	resourceLogs := appendProtoBytes(nil, 1, res)
+	resourceLogs = appendProtoBytes(resourceLogs, 2, scopeLogs)
+	return appendProtoBytes(nil, 1, resourceLogs)
+}
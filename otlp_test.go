@@ -0,0 +1,205 @@
+package logger
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Helper stand-in collector that forwards request bodies and content types
+func newTestCollector(t *testing.T) (*httptest.Server, chan []byte, chan string) {
+	t.Helper()
+	bodies := make(chan []byte, 10)
+	types := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/logs" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		types <- r.Header.Get("Content-Type")
+		bodies <- b
+	}))
+	t.Cleanup(srv.Close)
+	return srv, bodies, types
+}
+
+func receiveBody(t *testing.T, bodies chan []byte) []byte {
+	t.Helper()
+	select {
+	case b := <-bodies:
+		return b
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for export")
+	}
+	return nil
+}
+
+// Helper to decode a protobuf message into its fields. Values are
+// uint64 for varint and fixed fields and []byte for length delimited ones.
+func decodeProto(t *testing.T, b []byte) map[int][]any {
+	t.Helper()
+	fields := make(map[int][]any)
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		b = b[n:]
+		field, wire := int(tag>>3), int(tag&7)
+		switch wire {
+		case protoVarint:
+			v, n := binary.Uvarint(b)
+			b = b[n:]
+			fields[field] = append(fields[field], v)
+		case protoFixed64:
+			fields[field] = append(fields[field], binary.LittleEndian.Uint64(b))
+			b = b[8:]
+		case protoBytes:
+			l, n := binary.Uvarint(b)
+			b = b[n:]
+			fields[field] = append(fields[field], b[:l])
+			b = b[l:]
+		default:
+			t.Fatalf("unexpected wire type %d", wire)
+		}
+	}
+	return fields
+}
+
+func TestOTLPSink_JSON(t *testing.T) {
+	srv, bodies, types := newTestCollector(t)
+	s, err := NewOTLPSink(srv.URL+"/v1/logs", "checkout", OTLPJSON)
+	if err != nil {
+		t.Fatalf("Failed to create OTLP sink: %v", err)
+	}
+	s.SetResourceAttributes(Fields{"service.version": "1.2.3"})
+	s.SetBatchAge(time.Hour)
+	l := NewSinkLogger(false, false, s)
+
+	l.WithFields(Fields{
+		"trace_id": "0102030405060708090a0b0c0d0e0f10",
+		"span_id":  "0102030405060708",
+		"user":     "bob",
+		"attempt":  2,
+	}).Warnf("payment retried")
+	l.Close()
+
+	if ct := <-types; ct != "application/json" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	var req struct {
+		ResourceLogs []struct {
+			Resource struct {
+				Attributes []otlpKeyValue `json:"attributes"`
+			} `json:"resource"`
+			ScopeLogs []struct {
+				Scope struct {
+					Name string `json:"name"`
+				} `json:"scope"`
+				LogRecords []map[string]any `json:"logRecords"`
+			} `json:"scopeLogs"`
+		} `json:"resourceLogs"`
+	}
+	if err := json.Unmarshal(receiveBody(t, bodies), &req); err != nil {
+		t.Fatalf("invalid JSON body: %v", err)
+	}
+
+	res := map[string]otlpAnyValue{}
+	for _, kv := range req.ResourceLogs[0].Resource.Attributes {
+		res[kv.Key] = kv.Value
+	}
+	if *res["service.name"].StringValue != "checkout" || *res["service.version"].StringValue != "1.2.3" ||
+		*res["process.pid"].IntValue != int64(os.Getpid()) {
+		t.Errorf("unexpected resource attributes: %+v", req.ResourceLogs[0].Resource.Attributes)
+	}
+
+	scope := req.ResourceLogs[0].ScopeLogs[0]
+	if scope.Scope.Name != otlpScopeName || len(scope.LogRecords) != 1 {
+		t.Fatalf("unexpected scope logs: %+v", scope)
+	}
+	rec := scope.LogRecords[0]
+	if rec["severityNumber"] != float64(13) || rec["severityText"] != "WARN" {
+		t.Errorf("unexpected severity: %v", rec)
+	}
+	if rec["body"].(map[string]any)["stringValue"] != "payment retried" {
+		t.Errorf("unexpected body: %v", rec["body"])
+	}
+	if rec["traceId"] != "0102030405060708090a0b0c0d0e0f10" || rec["spanId"] != "0102030405060708" {
+		t.Errorf("unexpected trace context: %v", rec)
+	}
+	if _, err := strconv.ParseUint(rec["timeUnixNano"].(string), 10, 64); err != nil {
+		t.Errorf("expected timestamp as string: %v", rec["timeUnixNano"])
+	}
+	attrs := rec["attributes"].([]any)
+	if len(attrs) != 2 {
+		t.Fatalf("expected trace context to be removed from attributes: %v", attrs)
+	}
+	attempt := attrs[0].(map[string]any)
+	if attempt["key"] != "attempt" || attempt["value"].(map[string]any)["intValue"] != "2" {
+		t.Errorf("unexpected attribute: %v", attempt)
+	}
+}
+
+func TestOTLPSink_Protobuf(t *testing.T) {
+	srv, bodies, types := newTestCollector(t)
+	s, err := NewOTLPSink(srv.URL+"/v1/logs", "checkout", OTLPProtobuf)
+	if err != nil {
+		t.Fatalf("Failed to create OTLP sink: %v", err)
+	}
+	s.SetBatchAge(time.Hour)
+
+	now := time.Now()
+	s.WriteEntry(&Entry{
+		Time:    now,
+		Level:   LevelError,
+		Message: "boom",
+		Fields:  Fields{"span_id": []byte{1, 2, 3, 4, 5, 6, 7, 8}, "ok": false},
+	})
+	s.Close()
+
+	if ct := <-types; ct != "application/x-protobuf" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	req := decodeProto(t, receiveBody(t, bodies))
+	resourceLogs := decodeProto(t, req[1][0].([]byte))
+	resource := decodeProto(t, resourceLogs[1][0].([]byte))
+	first := decodeProto(t, resource[1][0].([]byte))
+	if string(first[1][0].([]byte)) != "service.name" {
+		t.Errorf("expected service.name first, got %q", first[1][0])
+	}
+
+	scopeLogs := decodeProto(t, resourceLogs[2][0].([]byte))
+	if scope := decodeProto(t, scopeLogs[1][0].([]byte)); string(scope[1][0].([]byte)) != otlpScopeName {
+		t.Errorf("unexpected scope: %v", scope)
+	}
+	rec := decodeProto(t, scopeLogs[2][0].([]byte))
+	if rec[1][0].(uint64) != uint64(now.UnixNano()) || rec[2][0].(uint64) != 17 || string(rec[3][0].([]byte)) != "ERROR" {
+		t.Errorf("unexpected record header: %v", rec)
+	}
+	if body := decodeProto(t, rec[5][0].([]byte)); string(body[1][0].([]byte)) != "boom" {
+		t.Errorf("unexpected body: %v", body)
+	}
+	if len(rec[6]) != 1 {
+		t.Fatalf("expected one attribute, got %v", rec[6])
+	}
+	attr := decodeProto(t, rec[6][0].([]byte))
+	if value := decodeProto(t, attr[2][0].([]byte)); string(attr[1][0].([]byte)) != "ok" || value[2][0].(uint64) != 0 {
+		t.Errorf("unexpected attribute: %v", attr)
+	}
+	if string(rec[10][0].([]byte)) != "\x01\x02\x03\x04\x05\x06\x07\x08" || rec[9] != nil {
+		t.Errorf("unexpected trace context: %v", rec)
+	}
+}
+
+func TestNewOTLPSink_InvalidArgs(t *testing.T) {
+	if _, err := NewOTLPSink("collector:4318", "svc", OTLPJSON); err == nil {
+		t.Error("Expected error for invalid endpoint")
+	}
+	if _, err := NewOTLPSink("http://collector:4318/v1/logs", "svc", OTLPEncoding(7)); err == nil {
+		t.Error("Expected error for invalid encoding")
+	}
+}
@@ -0,0 +1,286 @@
+package logger
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// RedactedMask replaces redacted data.
+const RedactedMask = "[REDACTED]"
+
+// Redactable is implemented by values that render themselves masked in
+// log messages and fields, whether or not a Redactor is configured.
+type Redactable interface {
+	Redacted() string
+}
+
+// Secret is a string that is always logged as RedactedMask.
+type Secret string
+
+// Redacted implements Redactable.
+func (s Secret) Redacted() string { return RedactedMask }
+
+// String masks the secret when formatted outside of the logger.
+func (s Secret) String() string { return RedactedMask }
+
+type redactRule struct {
+	re      *regexp.Regexp
+	replace func(re *regexp.Regexp, s string) string
+}
+
+// Redactor masks sensitive data before entries are written: regular
+// expression rules are applied to messages and string field values, and
+// fields with sensitive keys are masked entirely. The zero value has no
+// rules.
+type Redactor struct {
+	sync.RWMutex
+	rules []redactRule
+	keys  map[string]bool
+}
+
+var (
+	cardRE   = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	bearerRE = regexp.MustCompile(`(?i)\b(bearer\s+)[a-z0-9\-._~+/]+=*`)
+	emailRE  = regexp.MustCompile(`[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}`)
+)
+
+// NewRedactor creates a redactor masking credit card numbers, bearer
+// tokens and email addresses, and fields named password, passwd, secret,
+// token, authorization, api_key or apikey.
+func NewRedactor() *Redactor {
+	r := &Redactor{}
+	r.rules = []redactRule{
+		{cardRE, func(re *regexp.Regexp, s string) string {
+			return re.ReplaceAllStringFunc(s, func(m string) string {
+				if luhnValid(m) {
+					return RedactedMask
+				}
+				return m
+			})
+		}},
+		{bearerRE, replaceRegexp("${1}" + RedactedMask)},
+		{emailRE, replaceRegexp(RedactedMask)},
+	}
+	r.AddKeys("password", "passwd", "secret", "token", "authorization", "api_key", "apikey")
+	return r
+}
+
+func replaceRegexp(repl string) func(re *regexp.Regexp, s string) string {
+	return func(re *regexp.Regexp, s string) string {
+		return re.ReplaceAllString(s, repl)
+	}
+}
+
+// AddRule masks matches of pattern with replacement, which may refer to
+// submatches as in regexp.Regexp.ReplaceAllString.
+func (r *Redactor) AddRule(pattern, replacement string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid redaction pattern: %w", err)
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.rules = append(r.rules, redactRule{re, replaceRegexp(replacement)})
+	return nil
+}
+
+// AddKeys masks the values of fields with the given keys, compared case
+// insensitively. A key also matches as a part of a longer key separated
+// by '_', '-', '.' or camel case, e.g. "token" matches "user_token" and
+// "refreshToken".
+func (r *Redactor) AddKeys(keys ...string) {
+	r.Lock()
+	defer r.Unlock()
+	if r.keys == nil {
+		r.keys = make(map[string]bool, len(keys))
+	}
+	for _, k := range keys {
+		r.keys[normalizeKey(k)] = true
+	}
+}
+
+// RedactString applies the rules to s.
+func (r *Redactor) RedactString(s string) string {
+	r.RLock()
+	defer r.RUnlock()
+	for _, rule := range r.rules {
+		s = rule.replace(rule.re, s)
+	}
+	return s
+}
+
+// redactFields returns the fields with sensitive values masked. r may
+// be nil, in which case only Redactable values are masked.
+func (r *Redactor) redactFields(fields Fields) Fields {
+	// Avoid copying the fields when there is nothing to mask.
+	if r == nil && !hasRedactable(fields) {
+		return fields
+	}
+	out := make(Fields, len(fields))
+	for k, v := range fields {
+		out[k] = r.redactValue(k, v)
+	}
+	return out
+}
+
+// hasRedactable reports whether v is or contains a Redactable value.
+func hasRedactable(v any) bool {
+	switch v := v.(type) {
+	case Redactable:
+		return true
+	case Fields:
+		return hasRedactable(map[string]any(v))
+	case map[string]any:
+		for _, x := range v {
+			if hasRedactable(x) {
+				return true
+			}
+		}
+	case []any:
+		for _, x := range v {
+			if hasRedactable(x) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// redactValue masks v, a field value or an element of one. Maps and
+// slices are masked recursively.
+func (r *Redactor) redactValue(key string, v any) any {
+	if rv, ok := v.(Redactable); ok {
+		return rv.Redacted()
+	}
+	if r != nil && r.sensitiveKey(key) {
+		return RedactedMask
+	}
+	switch s := v.(type) {
+	case Fields:
+		return r.redactFields(s)
+	case map[string]any:
+		return map[string]any(r.redactFields(Fields(s)))
+	case []any:
+		out := make([]any, len(s))
+		for i, x := range s {
+			out[i] = r.redactValue("", x)
+		}
+		return out
+	case []string:
+		if r == nil {
+			return s
+		}
+		out := make([]string, len(s))
+		for i, x := range s {
+			out[i] = r.RedactString(x)
+		}
+		return out
+	}
+	if r == nil {
+		return v
+	}
+	switch s := v.(type) {
+	case string:
+		return r.RedactString(s)
+	case error:
+		// Keep the value unless its text needs masking.
+		if text := s.Error(); r.RedactString(text) != text {
+			return r.RedactString(text)
+		}
+	case fmt.Stringer:
+		if text := s.String(); r.RedactString(text) != text {
+			return r.RedactString(text)
+		}
+	}
+	return v
+}
+
+// sensitiveKey reports whether key, or a part of it, is a masked key.
+func (r *Redactor) sensitiveKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	key = normalizeKey(key)
+	r.RLock()
+	defer r.RUnlock()
+	if r.keys[key] {
+		return true
+	}
+	for k := range r.keys {
+		if strings.HasPrefix(key, k+"_") || strings.HasSuffix(key, "_"+k) || strings.Contains(key, "_"+k+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeKey lowercases key and separates its parts with '_', so that
+// "X-Api-Key", "x.api.key" and "xApiKey" all become "x_api_key".
+func normalizeKey(key string) string {
+	var b strings.Builder
+	for i, c := range key {
+		switch {
+		case c == '-' || c == '.' || c == ' ':
+			c = '_'
+		case c >= 'A' && c <= 'Z':
+			if i > 0 && key[i-1] >= 'a' && key[i-1] <= 'z' {
+				b.WriteByte('_')
+			}
+			c += 'a' - 'A'
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// maskArgs replaces Redactable format arguments with their masked form.
+func maskArgs(v []any) []any {
+	var masked []any
+	for i, a := range v {
+		ra, ok := a.(Redactable)
+		if !ok {
+			continue
+		}
+		if masked == nil {
+			masked = append([]any(nil), v...)
+		}
+		masked[i] = ra.Redacted()
+	}
+	if masked == nil {
+		return v
+	}
+	return masked
+}
+
+// luhnValid reports whether the digits in s pass the Luhn checksum used
+// by payment card numbers.
+func luhnValid(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
+
+// SetRedactor sets the redactor applied to messages and fields before
+// they are written to the text output and the sinks, nil disables it.
+// Redactable values are masked even without a redactor.
+func (l *Logger) SetRedactor(r *Redactor) {
+	l.Lock()
+	defer l.Unlock()
+	l.redactor = r
+}
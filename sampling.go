@@ -0,0 +1,216 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const defaultSummaryInterval = 10 * time.Second
+
+// SamplingPolicy limits how often entries with the same level and
+// message template (the format string) are logged: the first First
+// entries of every interval are logged, then every Thereafter-th one.
+type SamplingPolicy struct {
+	First      int
+	Thereafter int
+	Interval   time.Duration
+}
+
+type sampleKey struct {
+	level  Level
+	format string
+}
+
+type sampleCounter struct {
+	start      time.Time
+	n          int
+	suppressed int
+}
+
+// sampler drops entries according to per-level sampling policies and a
+// token bucket limiter, and periodically logs how many were suppressed.
+type sampler struct {
+	sync.Mutex
+	l        *Logger
+	policies map[Level]SamplingPolicy
+	counters map[sampleKey]*sampleCounter
+	rate     float64
+	burst    float64
+	tokens   float64
+	last     time.Time
+	limited  int
+	ticker   *time.Ticker
+	done     chan struct{}
+	stopped  chan struct{}
+	once     sync.Once
+}
+
+func newSampler(l *Logger) *sampler {
+	s := &sampler{
+		l:        l,
+		policies: make(map[Level]SamplingPolicy),
+		counters: make(map[sampleKey]*sampleCounter),
+		ticker:   time.NewTicker(defaultSummaryInterval),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// getSampler returns the logger's sampler, creating it on first use.
+func (l *Logger) getSampler() *sampler {
+	l.Lock()
+	defer l.Unlock()
+	if l.sampler == nil {
+		l.sampler = newSampler(l)
+	}
+	return l.sampler
+}
+
+// SetSampling sets the sampling policy for entries of the given level,
+// counted separately for every message template. A policy with a zero
+// interval removes sampling for the level. Sampling applies to loggers
+// derived with WithFields after this call. Fatal entries are never
+// sampled.
+func (l *Logger) SetSampling(level Level, policy SamplingPolicy) error {
+	if level == LevelFatal {
+		return fmt.Errorf("fatal entries cannot be sampled")
+	}
+	if policy.First < 0 || policy.Thereafter < 0 || policy.Interval < 0 {
+		return fmt.Errorf("invalid sampling policy: %+v", policy)
+	}
+	s := l.getSampler()
+	s.Lock()
+	defer s.Unlock()
+	if policy.Interval == 0 {
+		delete(s.policies, level)
+	} else {
+		s.policies[level] = policy
+	}
+	return nil
+}
+
+// SetRateLimit limits the logger to perSecond entries on average, with
+// bursts of up to burst entries. Fatal entries are never dropped. A zero
+// rate removes the limit.
+func (l *Logger) SetRateLimit(perSecond float64, burst int) error {
+	if perSecond < 0 || (perSecond > 0 && burst < 1) {
+		return fmt.Errorf("invalid rate limit: %v per second, burst %d", perSecond, burst)
+	}
+	s := l.getSampler()
+	s.Lock()
+	defer s.Unlock()
+	s.rate = perSecond
+	s.burst = float64(burst)
+	s.tokens = s.burst
+	s.last = s.l.now()
+	return nil
+}
+
+// SetSummaryInterval sets how often the number of entries dropped by
+// sampling and rate limiting is logged, every 10 seconds by default.
+func (l *Logger) SetSummaryInterval(d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("summary interval must be positive")
+	}
+	l.getSampler().ticker.Reset(d)
+	return nil
+}
+
+// allow reports whether an entry should be logged.
+func (s *sampler) allow(level Level, format string) bool {
+	now := s.l.now()
+	s.Lock()
+	defer s.Unlock()
+	if p, ok := s.policies[level]; ok {
+		key := sampleKey{level, format}
+		c := s.counters[key]
+		if c == nil {
+			c = &sampleCounter{start: now}
+			s.counters[key] = c
+		}
+		if now.Sub(c.start) >= p.Interval {
+			c.start, c.n = now, 0
+		}
+		c.n++
+		if c.n > p.First && (p.Thereafter == 0 || (c.n-p.First)%p.Thereafter != 0) {
+			c.suppressed++
+			countSuppressed(level, suppressSampled)
+			return false
+		}
+	}
+	if s.rate > 0 && level != LevelFatal {
+		s.tokens = min(s.burst, s.tokens+now.Sub(s.last).Seconds()*s.rate)
+		s.last = now
+		if s.tokens < 1 {
+			s.limited++
+			countSuppressed(level, suppressRateLimited)
+			return false
+		}
+		s.tokens--
+	}
+	return true
+}
+
+func (s *sampler) run() {
+	defer close(s.stopped)
+	for {
+		select {
+		case <-s.ticker.C:
+			s.summarize()
+		case <-s.done:
+			s.ticker.Stop()
+			s.summarize()
+			return
+		}
+	}
+}
+
+// summarize logs the suppressed counts since the last summary and
+// forgets idle message templates.
+func (s *sampler) summarize() {
+	type summary struct {
+		sampleKey
+		n int
+	}
+	now := s.l.now()
+	s.Lock()
+	var sums []summary
+	for key, c := range s.counters {
+		if c.suppressed > 0 {
+			sums = append(sums, summary{key, c.suppressed})
+			c.suppressed = 0
+		} else if p, ok := s.policies[key.level]; !ok || now.Sub(c.start) >= p.Interval {
+			delete(s.counters, key)
+		}
+	}
+	limited := s.limited
+	s.limited = 0
+	s.Unlock()
+
+	sort.Slice(sums, func(i, j int) bool {
+		if sums[i].level != sums[j].level {
+			return sums[i].level < sums[j].level
+		}
+		return sums[i].format < sums[j].format
+	})
+	for _, sum := range sums {
+		s.l.output(sum.level, s.l.label(sum.level),
+			fmt.Sprintf("suppressed %d similar messages: %q", sum.n, sum.format),
+			Fields{"suppressed": sum.n})
+	}
+	if limited > 0 {
+		s.l.output(LevelWarn, s.l.warnLabel,
+			fmt.Sprintf("rate limit suppressed %d messages", limited),
+			Fields{"suppressed": limited})
+	}
+}
+
+// stop logs a final summary and stops the summary timer.
+func (s *sampler) stop() {
+	s.once.Do(func() { close(s.done) })
+	<-s.stopped
+}
@@ -0,0 +1,128 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoggerSampling(t *testing.T) {
+	sink := &testSink{}
+	l := NewSinkLogger(false, false, sink)
+	if err := l.SetSampling(LevelWarn, SamplingPolicy{First: 3, Thereafter: 5, Interval: time.Hour}); err != nil {
+		t.Fatalf("Failed to set sampling: %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		l.Warnf("client %d misbehaves", i)
+	}
+	for i := 0; i < 5; i++ {
+		l.Warnf("other warning")
+		l.Errorf("not sampled")
+	}
+
+	var logged []string
+	for _, e := range sink.Entries() {
+		if e.Level == LevelWarn {
+			logged = append(logged, e.Message)
+		}
+	}
+	// First 3, then the 8th, 13th and 18th, plus the first 3 of the other template.
+	want := []string{"client 0 misbehaves", "client 1 misbehaves", "client 2 misbehaves",
+		"client 7 misbehaves", "client 12 misbehaves", "client 17 misbehaves",
+		"other warning", "other warning", "other warning"}
+	if strings.Join(logged, ",") != strings.Join(want, ",") {
+		t.Errorf("Unexpected sampled entries:\n got %v\nwant %v", logged, want)
+	}
+	if n := len(sink.Entries()) - len(logged); n != 5 {
+		t.Errorf("Expected all 5 error entries, got %d", n)
+	}
+
+	l.Close()
+	entries := sink.Entries()
+	summaries := entries[len(entries)-2:]
+	if summaries[0].Message != `suppressed 14 similar messages: "client %d misbehaves"` ||
+		summaries[0].Fields["suppressed"] != 14 || summaries[0].Level != LevelWarn {
+		t.Errorf("Unexpected summary entry: %+v", summaries[0])
+	}
+	if summaries[1].Message != `suppressed 2 similar messages: "other warning"` {
+		t.Errorf("Unexpected summary entry: %+v", summaries[1])
+	}
+}
+
+func TestLoggerSampling_Interval(t *testing.T) {
+	sink := &testSink{}
+	l := NewSinkLogger(false, false, sink)
+	defer l.Close()
+	l.SetSampling(LevelInfo, SamplingPolicy{First: 1, Interval: 50 * time.Millisecond})
+
+	l.Noticef("tick")
+	l.Noticef("tick")
+	time.Sleep(60 * time.Millisecond)
+	l.Noticef("tick")
+	if n := len(sink.Entries()); n != 2 {
+		t.Errorf("Expected sampling to restart with a new interval, got %d entries", n)
+	}
+}
+
+// Helper buffer safe for concurrent writes and reads.
+type syncBuffer struct {
+	sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.String()
+}
+
+func TestLoggerRateLimit(t *testing.T) {
+	l := newTestStdLogger(false, false, false, false, false)
+	var buf syncBuffer
+	l.logger.SetOutput(&buf)
+	if err := l.SetRateLimit(0.001, 2); err != nil {
+		t.Fatalf("Failed to set rate limit: %v", err)
+	}
+	if err := l.SetSummaryInterval(20 * time.Millisecond); err != nil {
+		t.Fatalf("Failed to set summary interval: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		l.Noticef("message %d", i)
+	}
+	if got := strings.Count(buf.String(), "message"); got != 2 {
+		t.Errorf("Expected the burst of 2 messages to be logged, got %d:\n%s", got, buf.String())
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if out := buf.String(); !strings.Contains(out, "[WRN] rate limit suppressed 3 messages") {
+		t.Errorf("Expected periodic summary, got:\n%s", out)
+	}
+	l.Close()
+}
+
+func TestLoggerSampling_InvalidArgs(t *testing.T) {
+	l := NewSinkLogger(false, false)
+	defer l.Close()
+	if err := l.SetSampling(LevelFatal, SamplingPolicy{First: 1, Interval: time.Second}); err == nil {
+		t.Error("Expected error sampling fatal entries")
+	}
+	if err := l.SetSampling(LevelInfo, SamplingPolicy{First: -1, Interval: time.Second}); err == nil {
+		t.Error("Expected error for negative First")
+	}
+	if err := l.SetRateLimit(10, 0); err == nil {
+		t.Error("Expected error for zero burst")
+	}
+	if err := l.SetSummaryInterval(0); err == nil {
+		t.Error("Expected error for zero summary interval")
+	}
+}
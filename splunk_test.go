@@ -0,0 +1,113 @@
+package logger
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type hecRequest struct {
+	auth   string
+	events []hecEvent
+}
+
+func TestSplunkHECSink(t *testing.T) {
+	reqs := make(chan hecRequest, 10)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := hecRequest{auth: r.Header.Get("Authorization")}
+		dec := json.NewDecoder(r.Body)
+		for {
+			var ev hecEvent
+			if err := dec.Decode(&ev); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Errorf("invalid event: %v", err)
+				break
+			}
+			req.events = append(req.events, ev)
+		}
+		w.Write([]byte(`{"text":"Success","code":0}`))
+		reqs <- req
+	}))
+	defer srv.Close()
+
+	s, err := NewSplunkHECSink(srv.URL+"/services/collector/event", "secret-token")
+	if err != nil {
+		t.Fatalf("Failed to create HEC sink: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	s.SetTLSConfig(&tls.Config{RootCAs: pool})
+	s.SetSource("billing")
+	s.SetIndex("main")
+	s.SetBatchAge(time.Hour)
+
+	l := NewSinkLogger(false, false, s)
+	l.Noticef("first")
+	l.WithFields(Fields{"order": 42, "message": "ignored"}).Errorf("second")
+	l.Close()
+
+	var req hecRequest
+	select {
+	case req = <-reqs:
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for HEC request")
+	}
+	if req.auth != "Splunk secret-token" {
+		t.Errorf("unexpected Authorization header %q", req.auth)
+	}
+	if len(req.events) != 2 {
+		t.Fatalf("expected 2 events, got %+v", req.events)
+	}
+	ev := req.events[1]
+	if ev.Source != "billing" || ev.Index != "main" || ev.SourceType != "_json" || ev.Time == 0 {
+		t.Errorf("unexpected event metadata: %+v", ev)
+	}
+	if ev.Event["message"] != "second" || ev.Event["level"] != "error" || ev.Event["order"] != float64(42) {
+		t.Errorf("unexpected event: %+v", ev.Event)
+	}
+}
+
+func TestHTTPSink_BasicAuthAndUntrustedTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	s, err := NewSplunkHECSink(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("Failed to create HEC sink: %v", err)
+	}
+	s.SetBatchAge(time.Hour)
+	s.SetMaxRetries(0)
+	defer s.Close()
+
+	s.WriteEntry(&Entry{Time: time.Now(), Message: "untrusted"})
+	if err := s.Flush(); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Fatalf("expected certificate error, got %v", err)
+	}
+
+	s.SetBasicAuth("user", "pass")
+	if got := s.header.Get("Authorization"); got != "Basic dXNlcjpwYXNz" {
+		t.Errorf("unexpected Authorization header %q", got)
+	}
+}
+
+func TestHTTPSink_SetBatchAgeZero(t *testing.T) {
+	s, err := NewSplunkHECSink("http://127.0.0.1:1", "token")
+	if err != nil {
+		t.Fatalf("Failed to create HEC sink: %v", err)
+	}
+	defer s.Close()
+	s.SetBatchAge(0)
+	s.Lock()
+	age := s.batchAge
+	s.Unlock()
+	if age != defaultHTTPBatchAge {
+		t.Errorf("Expected a zero batch age to restore the default, got %v", age)
+	}
+}
@@ -2,18 +2,28 @@ package logger
 
 import (
     "fmt"
-    "log"
     "log/syslog"
     "net/url"
     "os"
     "strings"
+    "time"
 )
 
+// syslogWriter is the subset of *syslog.Writer used by SysLogger.
+type syslogWriter interface {
+    Notice(m string) error
+    Warning(m string) error
+    Err(m string) error
+    Debug(m string) error
+    Close() error
+}
+
 // SysLogger provides a system logger implementation.
 type SysLogger struct {
-    writer *syslog.Writer
+    writer syslogWriter
     debug  bool
     trace  bool
+    dedup  *deduper
 }
 
 // GetSysLoggerTag generates a tag name for syslog based on the executable name.
@@ -71,44 +81,100 @@ func parseAddress(addr string) (network, destination string, err error) {
     }
 }
 
+// SetDedup collapses consecutive identical messages of the same level
+// into the first one and a "last message repeated N times" message,
+// written at the latest one window after the first repeat. A zero window
+// disables it. It must be called before the logger is used.
+func (l *SysLogger) SetDedup(window time.Duration) error {
+    if window < 0 {
+        return fmt.Errorf("dedup window must not be negative")
+    }
+    if l.dedup != nil {
+        l.dedup.flush()
+        l.dedup = nil
+    }
+    if window > 0 {
+        l.dedup = newDeduper(window, nil, func(level Level, msg string, repeated int) {
+            if err := l.write(level, repeatMessage(repeated)); err != nil {
+                reportError(nil, ErrorSourceSyslog, fmt.Errorf("failed to write to syslog: %w", err))
+            }
+        })
+    }
+    return nil
+}
+
 // logf handles generic log formatting and writes to syslog.
-func (l *SysLogger) logf(level func(string) error, format string, v ...interface{}) {
-    if err := level(fmt.Sprintf(format, v...)); err != nil {
-        log.Printf("failed to write to syslog: %v", err)
+func (l *SysLogger) logf(level Level, format string, v ...interface{}) {
+    msg := fmt.Sprintf(format, v...)
+    if l.dedup != nil && !l.dedup.check(level, msg) {
+        return
+    }
+    if err := l.write(level, msg); err != nil {
+        reportError(nil, ErrorSourceSyslog, fmt.Errorf("failed to write to syslog: %w", err))
     }
 }
 
+// write sends a message with the syslog priority matching the level.
+// Every failed message is counted once, however many destinations
+// failed.
+func (l *SysLogger) write(level Level, msg string) error {
+    var err error
+    switch level {
+    case LevelError, LevelFatal:
+        err = l.writer.Err(msg)
+    case LevelWarn:
+        err = l.writer.Warning(msg)
+    case LevelDebug:
+        err = l.writer.Debug(msg)
+    default:
+        err = l.writer.Notice(msg)
+    }
+    if err != nil {
+        metrics.syslogFailures.Add(1)
+    }
+    return err
+}
+
 // Noticef logs a notice message.
 func (l *SysLogger) Noticef(format string, v ...interface{}) {
-    l.logf(l.writer.Notice, format, v...)
+    l.logf(LevelInfo, format, v...)
 }
 
 // Warnf logs a warning message.
 func (l *SysLogger) Warnf(format string, v ...interface{}) {
-    l.logf(l.writer.Warning, format, v...)
+    l.logf(LevelWarn, format, v...)
 }
 
 // Errorf logs an error message.
 func (l *SysLogger) Errorf(format string, v ...interface{}) {
-    l.logf(l.writer.Err, format, v...)
+    l.logf(LevelError, format, v...)
 }
 
 // Debugf logs a debug message if debug is enabled.
 func (l *SysLogger) Debugf(format string, v ...interface{}) {
     if l.debug {
-        l.logf(l.writer.Debug, format, v...)
+        l.logf(LevelDebug, format, v...)
     }
 }
 
 // Tracef logs a trace message if trace is enabled.
 func (l *SysLogger) Tracef(format string, v ...interface{}) {
     if l.trace {
-        l.logf(l.writer.Notice, format, v...)
+        l.logf(LevelTrace, format, v...)
     }
 }
 
+// WriteEntry implements the Sink interface so that a SysLogger can
+// receive entries from a Logger.
+func (l *SysLogger) WriteEntry(e *Entry) error {
+    return l.write(e.Level, e.Message)
+}
+
 // Close closes the syslog writer.
 func (l *SysLogger) Close() error {
+    if l.dedup != nil {
+        l.dedup.flush()
+    }
     if l.writer != nil {
         return l.writer.Close()
     }
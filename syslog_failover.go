@@ -0,0 +1,355 @@
+package logger
+
+import (
+    "errors"
+    "fmt"
+    "log/syslog"
+    "net"
+    "os"
+    "strings"
+    "sync"
+    "time"
+)
+
+// SysLogMode selects how a SysLogger with several destinations
+// distributes messages between them.
+type SysLogMode int
+
+const (
+    // SysLogFailover sends every message to the first healthy destination,
+    // in the order they were given.
+    SysLogFailover SysLogMode = iota
+    // SysLogRoundRobin spreads messages across the healthy destinations.
+    SysLogRoundRobin
+    // SysLogBroadcast sends every message to all healthy destinations.
+    SysLogBroadcast
+)
+
+// Default delay before an unhealthy syslog destination is tried again.
+const defaultSysLogRetryInterval = 30 * time.Second
+
+// Limits for connecting to and writing to a remote syslog destination,
+// so that an unreachable server cannot stall logging indefinitely.
+const (
+    sysLogDialTimeout  = 5 * time.Second
+    sysLogWriteTimeout = 5 * time.Second
+)
+
+// SysLogDestinationStatus reports the health of a single syslog destination.
+type SysLogDestinationStatus struct {
+    Addr      string
+    Healthy   bool
+    Failures  int
+    LastError error
+    DownSince time.Time
+}
+
+type sysLogDestination struct {
+    addr      string
+    network   string
+    raddr     string
+    writer    syslogWriter
+    dialing   bool
+    healthy   bool
+    failures  int
+    lastErr   error
+    downSince time.Time
+}
+
+// failoverWriter implements syslogWriter on top of an ordered
+// list of syslog destinations.
+type failoverWriter struct {
+    sync.Mutex
+    dests         []*sysLogDestination
+    mode          SysLogMode
+    next          int
+    retryInterval time.Duration
+    tag           string
+    closed        bool
+}
+
+// NewMultiSysLogger creates a system logger that writes to an ordered list
+// of syslog destinations. Each address uses the same format as NewSysLogger.
+// Destinations that fail are skipped until the retry interval has elapsed,
+// after which they are tried again; in failover mode this means traffic
+// returns to the primary once it recovers.
+func NewMultiSysLogger(addrs []string, mode SysLogMode, debug, trace bool) (*SysLogger, error) {
+    if len(addrs) == 0 {
+        return nil, fmt.Errorf("no syslog destinations given")
+    }
+    switch mode {
+    case SysLogFailover, SysLogRoundRobin, SysLogBroadcast:
+    default:
+        return nil, fmt.Errorf("invalid syslog mode: %d", mode)
+    }
+
+    w := &failoverWriter{
+        mode:          mode,
+        retryInterval: defaultSysLogRetryInterval,
+        tag:           GetSysLoggerTag(),
+    }
+    for _, addr := range addrs {
+        network, destination, err := parseAddress(addr)
+        if err != nil {
+            return nil, fmt.Errorf("failed to parse syslog address %q: %v", addr, err)
+        }
+        w.dests = append(w.dests, &sysLogDestination{
+            addr:    addr,
+            network: network,
+            raddr:   destination,
+        })
+    }
+
+    // Connect eagerly so that the initial health is known, but only
+    // give up if none of the destinations can be reached.
+    var errs []error
+    w.Lock()
+    for _, d := range w.dests {
+        if err := w.dial(d); err != nil {
+            errs = append(errs, err)
+        }
+    }
+    w.Unlock()
+    if len(errs) == len(w.dests) {
+        return nil, fmt.Errorf("failed to connect to syslog: %w", errors.Join(errs...))
+    }
+
+    return &SysLogger{
+        writer: w,
+        debug:  debug,
+        trace:  trace,
+    }, nil
+}
+
+// SetRetryInterval sets how long an unhealthy syslog destination is
+// skipped before it is tried again.
+func (l *SysLogger) SetRetryInterval(d time.Duration) error {
+    w, ok := l.writer.(*failoverWriter)
+    if !ok {
+        return fmt.Errorf("can set retry interval only for multi-destination syslog")
+    }
+    w.Lock()
+    w.retryInterval = d
+    w.Unlock()
+    return nil
+}
+
+// Destinations returns the health of each syslog destination, in the
+// order they were configured.
+func (l *SysLogger) Destinations() []SysLogDestinationStatus {
+    w, ok := l.writer.(*failoverWriter)
+    if !ok {
+        return nil
+    }
+    w.Lock()
+    defer w.Unlock()
+    status := make([]SysLogDestinationStatus, 0, len(w.dests))
+    for _, d := range w.dests {
+        status = append(status, SysLogDestinationStatus{
+            Addr:      d.addr,
+            Healthy:   d.healthy,
+            Failures:  d.failures,
+            LastError: d.lastErr,
+            DownSince: d.downSince,
+        })
+    }
+    return status
+}
+
+// dial connects a destination. Lock must be held; it is released while
+// connecting so that a slow destination does not block the others, which
+// skip the destination until the dial completes.
+func (w *failoverWriter) dial(d *sysLogDestination) error {
+    d.dialing = true
+    w.Unlock()
+    var (
+        sw  syslogWriter
+        err error
+    )
+    if d.network == "" {
+        sw, err = syslog.New(syslog.LOG_DAEMON|syslog.LOG_NOTICE, w.tag)
+    } else {
+        sw, err = dialSysLog(d.network, d.raddr, w.tag)
+    }
+    w.Lock()
+    d.dialing = false
+    if err == nil && w.closed {
+        sw.Close()
+        err = fmt.Errorf("syslog writer is closed")
+    }
+    if err != nil {
+        w.markDown(d, err)
+        return fmt.Errorf("%s: %w", d.addr, err)
+    }
+    d.writer = sw
+    d.healthy = true
+    d.failures = 0
+    return nil
+}
+
+func (w *failoverWriter) markDown(d *sysLogDestination, err error) {
+    if d.writer != nil {
+        d.writer.Close()
+        d.writer = nil
+    }
+    if d.healthy || d.downSince.IsZero() {
+        d.downSince = time.Now()
+    }
+    d.healthy = false
+    d.failures++
+    d.lastErr = err
+    reportError(nil, ErrorSourceSyslog, fmt.Errorf("syslog destination %s failed: %w", d.addr, err))
+}
+
+// usable reports whether a destination should be tried for the next message.
+func (w *failoverWriter) usable(d *sysLogDestination) bool {
+    if d.dialing || w.closed {
+        return false
+    }
+    if d.healthy {
+        return true
+    }
+    if time.Since(d.downSince) < w.retryInterval {
+        return false
+    }
+    // Restart the retry interval whether or not the attempt succeeds.
+    d.downSince = time.Now()
+    return true
+}
+
+func (w *failoverWriter) sendTo(d *sysLogDestination, send func(syslogWriter, string) error, m string) error {
+    if d.writer == nil {
+        if err := w.dial(d); err != nil {
+            return err
+        }
+    }
+    if err := send(d.writer, m); err != nil {
+        w.markDown(d, err)
+        return fmt.Errorf("%s: %w", d.addr, err)
+    }
+    d.healthy = true
+    d.failures = 0
+    return nil
+}
+
+func (w *failoverWriter) write(send func(syslogWriter, string) error, m string) error {
+    w.Lock()
+    defer w.Unlock()
+
+    n := len(w.dests)
+    start := 0
+    if w.mode == SysLogRoundRobin {
+        start = w.next
+        w.next = (w.next + 1) % n
+    }
+
+    var errs []error
+    tried, sent := 0, 0
+    for i := 0; i < n; i++ {
+        d := w.dests[(start+i)%n]
+        if !w.usable(d) {
+            continue
+        }
+        tried++
+        if err := w.sendTo(d, send, m); err != nil {
+            errs = append(errs, err)
+            continue
+        }
+        sent++
+        if w.mode != SysLogBroadcast {
+            return nil
+        }
+    }
+
+    if sent > 0 {
+        return nil
+    }
+    if tried == 0 {
+        return fmt.Errorf("no healthy syslog destination")
+    }
+    return errors.Join(errs...)
+}
+
+func (w *failoverWriter) Notice(m string) error {
+    return w.write(syslogWriter.Notice, m)
+}
+
+func (w *failoverWriter) Warning(m string) error {
+    return w.write(syslogWriter.Warning, m)
+}
+
+func (w *failoverWriter) Err(m string) error {
+    return w.write(syslogWriter.Err, m)
+}
+
+func (w *failoverWriter) Debug(m string) error {
+    return w.write(syslogWriter.Debug, m)
+}
+
+func (w *failoverWriter) Close() error {
+    w.Lock()
+    defer w.Unlock()
+    w.closed = true
+    var errs []error
+    for _, d := range w.dests {
+        if d.writer != nil {
+            if err := d.writer.Close(); err != nil {
+                errs = append(errs, err)
+            }
+            d.writer = nil
+        }
+    }
+    return errors.Join(errs...)
+}
+
+// netSysLogWriter writes messages to a remote syslog server in the same
+// format as log/syslog, but over a connection dialed with a timeout and
+// with a deadline on every write.
+type netSysLogWriter struct {
+    conn     net.Conn
+    hostname string
+    tag      string
+}
+
+func dialSysLog(network, raddr, tag string) (*netSysLogWriter, error) {
+    conn, err := net.DialTimeout(network, raddr, sysLogDialTimeout)
+    if err != nil {
+        return nil, err
+    }
+    hostname := localHostname()
+    if hostname == "" {
+        hostname = "localhost"
+    }
+    return &netSysLogWriter{conn: conn, hostname: hostname, tag: tag}, nil
+}
+
+func (w *netSysLogWriter) write(p syslog.Priority, msg string) error {
+    nl := ""
+    if !strings.HasSuffix(msg, "\n") {
+        nl = "\n"
+    }
+    w.conn.SetWriteDeadline(time.Now().Add(sysLogWriteTimeout))
+    _, err := fmt.Fprintf(w.conn, "<%d>%s %s %s[%d]: %s%s",
+        p, time.Now().Format(time.RFC3339), w.hostname, w.tag, os.Getpid(), msg, nl)
+    return err
+}
+
+func (w *netSysLogWriter) Notice(m string) error {
+    return w.write(syslog.LOG_NOTICE, m)
+}
+
+func (w *netSysLogWriter) Warning(m string) error {
+    return w.write(syslog.LOG_WARNING, m)
+}
+
+func (w *netSysLogWriter) Err(m string) error {
+    return w.write(syslog.LOG_ERR, m)
+}
+
+func (w *netSysLogWriter) Debug(m string) error {
+    return w.write(syslog.LOG_DEBUG, m)
+}
+
+func (w *netSysLogWriter) Close() error {
+    return w.conn.Close()
+}
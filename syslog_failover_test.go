@@ -0,0 +1,198 @@
+package logger
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Helper to start a UDP listener that collects received syslog packets
+func newTestUDPSyslog(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	ch := make(chan string, 100)
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			ch <- string(buf[:n])
+		}
+	}()
+	return "udp://" + conn.LocalAddr().String(), ch
+}
+
+func expectSyslogMsg(t *testing.T, ch <-chan string, msg string) {
+	t.Helper()
+	select {
+	case m := <-ch:
+		if !strings.Contains(m, msg) {
+			t.Fatalf("expected message containing %q, got %q", msg, m)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for %q", msg)
+	}
+}
+
+func expectNoSyslogMsg(t *testing.T, ch <-chan string) {
+	t.Helper()
+	select {
+	case m := <-ch:
+		t.Fatalf("expected no message, got %q", m)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestNewMultiSysLogger_InvalidArgs(t *testing.T) {
+	if _, err := NewMultiSysLogger(nil, SysLogFailover, false, false); err == nil {
+		t.Fatal("Expected error for empty destination list, got nil")
+	}
+	if _, err := NewMultiSysLogger([]string{"invalid://address"}, SysLogFailover, false, false); err == nil {
+		t.Fatal("Expected error for invalid address, got nil")
+	}
+	if _, err := NewMultiSysLogger([]string{"udp://127.0.0.1:514"}, SysLogMode(42), false, false); err == nil {
+		t.Fatal("Expected error for invalid mode, got nil")
+	}
+}
+
+func TestMultiSysLogger_Failover(t *testing.T) {
+	// Reserve an address for the primary and leave nothing listening on it.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	primary := ln.Addr().String()
+	ln.Close()
+
+	secondary, secCh := newTestUDPSyslog(t)
+
+	logger, err := NewMultiSysLogger([]string{"tcp://" + primary, secondary}, SysLogFailover, false, false)
+	if err != nil {
+		t.Fatalf("Failed to create multi syslogger: %v", err)
+	}
+	defer logger.Close()
+
+	status := logger.Destinations()
+	if len(status) != 2 || status[0].Healthy || !status[1].Healthy {
+		t.Fatalf("unexpected destination status: %+v", status)
+	}
+
+	logger.Noticef("goes to secondary")
+	expectSyslogMsg(t, secCh, "goes to secondary")
+
+	// Bring the primary back and make it eligible for retry.
+	ln, err = net.Listen("tcp", primary)
+	if err != nil {
+		t.Skipf("unable to re-listen on %s: %v", primary, err)
+	}
+	defer ln.Close()
+	priCh := make(chan string, 10)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		r := bufio.NewReader(c)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			priCh <- line
+		}
+	}()
+
+	logger.SetRetryInterval(0)
+	logger.Errorf("back on primary")
+	expectSyslogMsg(t, priCh, "back on primary")
+	expectNoSyslogMsg(t, secCh)
+
+	if status := logger.Destinations(); !status[0].Healthy {
+		t.Fatalf("expected primary to be healthy again: %+v", status)
+	}
+}
+
+func TestMultiSysLogger_RoundRobin(t *testing.T) {
+	a, aCh := newTestUDPSyslog(t)
+	b, bCh := newTestUDPSyslog(t)
+
+	logger, err := NewMultiSysLogger([]string{a, b}, SysLogRoundRobin, false, false)
+	if err != nil {
+		t.Fatalf("Failed to create multi syslogger: %v", err)
+	}
+	defer logger.Close()
+
+	logger.Noticef("first")
+	logger.Noticef("second")
+	expectSyslogMsg(t, aCh, "first")
+	expectSyslogMsg(t, bCh, "second")
+}
+
+func TestMultiSysLogger_Broadcast(t *testing.T) {
+	a, aCh := newTestUDPSyslog(t)
+	b, bCh := newTestUDPSyslog(t)
+
+	logger, err := NewMultiSysLogger([]string{a, b}, SysLogBroadcast, false, false)
+	if err != nil {
+		t.Fatalf("Failed to create multi syslogger: %v", err)
+	}
+	defer logger.Close()
+
+	logger.Warnf("to everyone")
+	expectSyslogMsg(t, aCh, "to everyone")
+	expectSyslogMsg(t, bCh, "to everyone")
+}
+
+func TestSysLogger_SetRetryIntervalSingle(t *testing.T) {
+	logger, err := NewSysLogger("udp://127.0.0.1:514", false, false)
+	if err != nil {
+		t.Fatalf("Failed to create remote syslogger: %v", err)
+	}
+	defer logger.Close()
+
+	if err := logger.SetRetryInterval(time.Second); err == nil {
+		t.Fatal("Expected error setting retry interval on single destination syslog")
+	}
+	if logger.Destinations() != nil {
+		t.Fatal("Expected no destination status for single destination syslog")
+	}
+}
+
+func TestMultiSysLogger_SkipsDialingDestination(t *testing.T) {
+	a, aCh := newTestUDPSyslog(t)
+	b, bCh := newTestUDPSyslog(t)
+
+	logger, err := NewMultiSysLogger([]string{a, b}, SysLogFailover, false, false)
+	if err != nil {
+		t.Fatalf("Failed to create multi syslogger: %v", err)
+	}
+	defer logger.Close()
+
+	// A destination whose connection is still being dialed must not
+	// hold up messages for the others.
+	w := logger.writer.(*failoverWriter)
+	w.Lock()
+	w.dests[0].dialing = true
+	w.Unlock()
+
+	logger.Noticef("while dialing")
+	expectSyslogMsg(t, bCh, "while dialing")
+	expectNoSyslogMsg(t, aCh)
+
+	w.Lock()
+	w.dests[0].dialing = false
+	w.Unlock()
+
+	logger.Noticef("dialed")
+	expectSyslogMsg(t, aCh, "<5>")
+}
@@ -2,6 +2,9 @@ package logger
 
 import (
 	"testing"
+	"time"
+
+	"github.com/ninepeach/logger/syslogd"
 )
 
 func TestGetSysLoggerTag(t *testing.T) {
@@ -34,8 +37,16 @@ func TestNewSysLogger_InvalidRemoteAddress(t *testing.T) {
 }
 
 func TestNewSysLogger_Remote(t *testing.T) {
-	// Use a local syslog server address for testing, modify as needed
-	remoteAddr := "udp://127.0.0.1:514"
+	// Use a local syslog receiver to verify what is sent
+	collector := syslogd.NewCollector()
+	server := syslogd.NewServer(collector)
+	defer server.Close()
+	addr, err := server.ListenUDP("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start syslog receiver: %v", err)
+	}
+
+	remoteAddr := "udp://" + addr.String()
 	logger, err := NewSysLogger(remoteAddr, true, false)
 	if err != nil {
 		t.Fatalf("Failed to create remote syslogger: %v", err)
@@ -45,6 +56,29 @@ func TestNewSysLogger_Remote(t *testing.T) {
 	logger.Noticef("This is a notice log to remote syslog")
 	logger.Warnf("This is a warning log to remote syslog")
 	logger.Debugf("Debug logs should be visible if enabled")
+	logger.Tracef("Trace logs should not be sent")
+
+	records, err := collector.Wait(3, 2*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to receive syslog records: %v", err)
+	}
+	expected := []struct {
+		severity int
+		message  string
+	}{
+		{syslogd.SevNotice, "This is a notice log to remote syslog"},
+		{syslogd.SevWarning, "This is a warning log to remote syslog"},
+		{syslogd.SevDebug, "Debug logs should be visible if enabled"},
+	}
+	for i, exp := range expected {
+		r := records[i]
+		if r.Severity != exp.severity || r.Message != exp.message || r.AppName != GetSysLoggerTag() {
+			t.Errorf("Expected %q with severity %d, got %+v", exp.message, exp.severity, r)
+		}
+	}
+	if _, err := collector.Wait(4, 100*time.Millisecond); err == nil {
+		t.Errorf("Expected no trace record, got %+v", collector.Records()[3])
+	}
 }
 
 func TestParseAddress(t *testing.T) {
@@ -89,3 +123,29 @@ func TestSysLogger_Close(t *testing.T) {
 		t.Errorf("Expected no error on Close, got: %v", err)
 	}
 }
+
+func TestSysLogger_WriteEntry(t *testing.T) {
+	collector := syslogd.NewCollector()
+	server := syslogd.NewServer(collector)
+	defer server.Close()
+	addr, err := server.ListenUDP("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start syslog receiver: %v", err)
+	}
+
+	sl, err := NewSysLogger("udp://"+addr.String(), false, false)
+	if err != nil {
+		t.Fatalf("Failed to create remote syslogger: %v", err)
+	}
+	l := NewSinkLogger(false, false, sl)
+	defer l.Close()
+
+	l.Errorf("error through sink")
+	records, err := collector.Wait(1, 2*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to receive syslog records: %v", err)
+	}
+	if records[0].Severity != syslogd.SevErr || records[0].Message != "error through sink" {
+		t.Errorf("Unexpected record: %+v", records[0])
+	}
+}
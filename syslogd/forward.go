@@ -0,0 +1,36 @@
+package syslogd
+
+// Printer is the subset of the logger API used to relay records. It is
+// satisfied by *logger.Logger and *logger.SysLogger.
+type Printer interface {
+	Noticef(format string, v ...any)
+	Warnf(format string, v ...any)
+	Errorf(format string, v ...any)
+	Debugf(format string, v ...any)
+}
+
+// Forward returns a Handler that relays records to p, mapping the syslog
+// severity to the closest logger level. Emergency, alert and critical
+// records are logged as errors, a relay must not terminate the process.
+func Forward(p Printer) Handler {
+	return HandlerFunc(func(r *Record) {
+		origin := r.AppName
+		if r.ProcID != "" {
+			origin += "[" + r.ProcID + "]"
+		}
+		if r.Hostname != "" {
+			origin = r.Hostname + " " + origin
+		}
+
+		switch {
+		case r.Severity <= SevErr:
+			p.Errorf("%s: %s", origin, r.Message)
+		case r.Severity == SevWarning:
+			p.Warnf("%s: %s", origin, r.Message)
+		case r.Severity == SevDebug:
+			p.Debugf("%s: %s", origin, r.Message)
+		default:
+			p.Noticef("%s: %s", origin, r.Message)
+		}
+	})
+}
@@ -0,0 +1,208 @@
+// Package syslogd implements a small syslog receiver. It accepts RFC 3164
+// and RFC 5424 messages over UDP, TCP, TLS and unix datagram sockets and
+// hands them to a Handler as parsed records. It is meant to be used as a
+// test harness for the logger package and as a lightweight relay.
+package syslogd
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format identifies the syslog protocol version of a record.
+type Format int
+
+const (
+	// RFC3164 is the BSD syslog format, also produced by log/syslog.
+	RFC3164 Format = iota
+	// RFC5424 is the structured syslog format.
+	RFC5424
+)
+
+// Severity levels as defined by RFC 5424.
+const (
+	SevEmerg = iota
+	SevAlert
+	SevCrit
+	SevErr
+	SevWarning
+	SevNotice
+	SevInfo
+	SevDebug
+)
+
+// Record is a single parsed syslog message.
+type Record struct {
+	Format         Format
+	Facility       int
+	Severity       int
+	Timestamp      time.Time
+	Hostname       string
+	AppName        string
+	ProcID         string
+	MsgID          string
+	StructuredData string
+	Message        string
+}
+
+// Parse decodes a single syslog message. The format is detected from
+// the version field that follows the priority.
+func Parse(b []byte) (*Record, error) {
+	b = bytes.TrimRight(b, "\r\n\x00")
+	pri, rest, err := parsePriority(b)
+	if err != nil {
+		return nil, err
+	}
+	r := &Record{Facility: pri / 8, Severity: pri % 8}
+	if len(rest) > 1 && rest[0] == '1' && rest[1] == ' ' {
+		r.Format = RFC5424
+		err = parse5424(r, string(rest[2:]))
+	} else {
+		r.Format = RFC3164
+		err = parse3164(r, string(rest))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func parsePriority(b []byte) (int, []byte, error) {
+	if len(b) < 3 || b[0] != '<' {
+		return 0, nil, fmt.Errorf("missing priority")
+	}
+	end := bytes.IndexByte(b, '>')
+	if end < 2 || end > 4 {
+		return 0, nil, fmt.Errorf("invalid priority")
+	}
+	pri, err := strconv.Atoi(string(b[1:end]))
+	if err != nil || pri > 191 {
+		return 0, nil, fmt.Errorf("invalid priority %q", b[1:end])
+	}
+	return pri, b[end+1:], nil
+}
+
+// nextField splits off the next space separated field.
+func nextField(s string) (string, string) {
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
+
+// nilValue maps the RFC 5424 nil value to an empty string.
+func nilValue(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+func parse5424(r *Record, s string) error {
+	var ts string
+	ts, s = nextField(s)
+	if ts != "-" {
+		t, err := time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			return fmt.Errorf("invalid timestamp %q", ts)
+		}
+		r.Timestamp = t
+	}
+
+	var f string
+	f, s = nextField(s)
+	r.Hostname = nilValue(f)
+	f, s = nextField(s)
+	r.AppName = nilValue(f)
+	f, s = nextField(s)
+	r.ProcID = nilValue(f)
+	f, s = nextField(s)
+	r.MsgID = nilValue(f)
+
+	switch {
+	case strings.HasPrefix(s, "-"):
+		s = s[1:]
+	case strings.HasPrefix(s, "["):
+		n, err := structuredDataLen(s)
+		if err != nil {
+			return err
+		}
+		r.StructuredData, s = s[:n], s[n:]
+	default:
+		return fmt.Errorf("missing structured data")
+	}
+	s = strings.TrimPrefix(s, " ")
+	r.Message = strings.TrimPrefix(s, "\ufeff")
+	return nil
+}
+
+// structuredDataLen returns the length of the structured data elements
+// at the start of s, honoring escaped characters in parameter values.
+func structuredDataLen(s string) (int, error) {
+	i := 0
+	for i < len(s) && s[i] == '[' {
+		inValue := false
+		for i++; i < len(s); i++ {
+			c := s[i]
+			if inValue {
+				if c == '\\' {
+					i++
+				} else if c == '"' {
+					inValue = false
+				}
+				continue
+			}
+			if c == '"' {
+				inValue = true
+			} else if c == ']' {
+				break
+			}
+		}
+		if i >= len(s) {
+			return 0, fmt.Errorf("unterminated structured data")
+		}
+		i++
+	}
+	return i, nil
+}
+
+func parse3164(r *Record, s string) error {
+	// log/syslog uses RFC 3339 timestamps for remote destinations and
+	// the traditional "Jan _2 15:04:05" stamp for local ones.
+	if len(s) >= len(time.Stamp) {
+		if t, err := time.ParseInLocation(time.Stamp, s[:len(time.Stamp)], time.Local); err == nil {
+			now := time.Now()
+			r.Timestamp = t.AddDate(now.Year(), 0, 0)
+			s = strings.TrimPrefix(s[len(time.Stamp):], " ")
+		}
+	}
+	if r.Timestamp.IsZero() {
+		f, rest := nextField(s)
+		if t, err := time.Parse(time.RFC3339Nano, f); err == nil {
+			r.Timestamp = t
+			s = rest
+		}
+	}
+
+	// The hostname is optional, a field ending with ':' or containing
+	// '[' is the tag.
+	if f, rest := nextField(s); rest != "" && !strings.HasSuffix(f, ":") && !strings.Contains(f, "[") {
+		r.Hostname = f
+		s = rest
+	}
+
+	if i := strings.Index(s, ": "); i >= 0 && !strings.Contains(s[:i], " ") {
+		tag := s[:i]
+		s = s[i+2:]
+		if b := strings.IndexByte(tag, '['); b >= 0 && strings.HasSuffix(tag, "]") {
+			r.ProcID = tag[b+1 : len(tag)-1]
+			tag = tag[:b]
+		}
+		r.AppName = tag
+	}
+	r.Message = s
+	return nil
+}
@@ -0,0 +1,80 @@
+package syslogd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse5424(t *testing.T) {
+	msg := `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Appli\]cation"] ` + "\ufeff" + `An application event`
+	r, err := Parse([]byte(msg))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if r.Format != RFC5424 || r.Facility != 20 || r.Severity != SevNotice {
+		t.Errorf("Unexpected header: %+v", r)
+	}
+	if !r.Timestamp.Equal(time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC)) {
+		t.Errorf("Unexpected timestamp: %v", r.Timestamp)
+	}
+	if r.Hostname != "mymachine.example.com" || r.AppName != "evntslog" || r.ProcID != "" || r.MsgID != "ID47" {
+		t.Errorf("Unexpected fields: %+v", r)
+	}
+	if r.StructuredData != `[exampleSDID@32473 iut="3" eventSource="Appli\]cation"]` {
+		t.Errorf("Unexpected structured data: %q", r.StructuredData)
+	}
+	if r.Message != "An application event" {
+		t.Errorf("Unexpected message: %q", r.Message)
+	}
+}
+
+func TestParse5424_NilValues(t *testing.T) {
+	r, err := Parse([]byte("<14>1 - - - - - -"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !r.Timestamp.IsZero() || r.Hostname != "" || r.Message != "" {
+		t.Errorf("Unexpected record: %+v", r)
+	}
+}
+
+func TestParse3164(t *testing.T) {
+	tests := []struct {
+		msg      string
+		host     string
+		app      string
+		procID   string
+		message  string
+		severity int
+	}{
+		// Remote format produced by log/syslog
+		{"<28>2024-05-01T10:00:00+02:00 myhost app[123]: disk is full\n", "myhost", "app", "123", "disk is full", SevWarning},
+		// Local format produced by log/syslog
+		{"<29>Oct 11 22:14:15 app[42]: starting up\n", "", "app", "42", "starting up", SevNotice},
+		// Classic BSD format
+		{"<34>Oct  1 22:14:15 mymachine su: 'su root' failed", "mymachine", "su", "", "'su root' failed", SevCrit},
+	}
+
+	for _, test := range tests {
+		r, err := Parse([]byte(test.msg))
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", test.msg, err)
+			continue
+		}
+		if r.Format != RFC3164 || r.Timestamp.IsZero() {
+			t.Errorf("For %q, unexpected header: %+v", test.msg, r)
+		}
+		if r.Hostname != test.host || r.AppName != test.app || r.ProcID != test.procID ||
+			r.Message != test.message || r.Severity != test.severity {
+			t.Errorf("For %q, unexpected record: %+v", test.msg, r)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, msg := range []string{"", "no priority", "<abc>1 - - - - - -", "<999>test", "<14>1 bad-time - - - - -", "<14>1 - - - - - [unterminated"} {
+		if _, err := Parse([]byte(msg)); err == nil {
+			t.Errorf("Expected error for %q, got nil", msg)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package syslogd
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Maximum size of a single syslog message accepted by the server.
+const maxMessageSize = 64 * 1024
+
+// Handler processes records received by a Server.
+type Handler interface {
+	Handle(r *Record)
+}
+
+// HandlerFunc adapts an ordinary function to a Handler.
+type HandlerFunc func(r *Record)
+
+// Handle calls f(r).
+func (f HandlerFunc) Handle(r *Record) {
+	f(r)
+}
+
+// Server receives syslog messages on any number of listeners and passes
+// the parsed records to its handler. Messages that cannot be parsed are
+// reported to the optional error callback and otherwise dropped.
+type Server struct {
+	sync.Mutex
+	handler   Handler
+	onError   func(error)
+	listeners []io.Closer
+	conns     map[net.Conn]struct{}
+	wg        sync.WaitGroup
+	closed    bool
+}
+
+// NewServer creates a server delivering records to h.
+func NewServer(h Handler) *Server {
+	return &Server{
+		handler: h,
+		conns:   make(map[net.Conn]struct{}),
+	}
+}
+
+// SetErrorHandler sets a callback invoked for messages that cannot be
+// parsed or read.
+func (s *Server) SetErrorHandler(f func(error)) {
+	s.Lock()
+	s.onError = f
+	s.Unlock()
+}
+
+func (s *Server) reportError(err error) {
+	s.Lock()
+	f := s.onError
+	s.Unlock()
+	if f != nil {
+		f(err)
+	}
+}
+
+func (s *Server) track(c io.Closer) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.closed {
+		c.Close()
+		return fmt.Errorf("server closed")
+	}
+	s.listeners = append(s.listeners, c)
+	return nil
+}
+
+func (s *Server) dispatch(b []byte) {
+	r, err := Parse(b)
+	if err != nil {
+		s.reportError(fmt.Errorf("unable to parse syslog message %q: %w", b, err))
+		return
+	}
+	s.handler.Handle(r)
+}
+
+// ListenUDP starts receiving datagrams on addr and returns the bound address.
+func (s *Server) ListenUDP(addr string) (net.Addr, error) {
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return s.servePacket(pc)
+}
+
+// ListenUnix starts receiving datagrams on a unix socket at path. Any
+// stale socket file is removed first.
+func (s *Server) ListenUnix(path string) (net.Addr, error) {
+	os.Remove(path)
+	pc, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		return nil, err
+	}
+	return s.servePacket(pc)
+}
+
+func (s *Server) servePacket(pc net.PacketConn) (net.Addr, error) {
+	if err := s.track(pc); err != nil {
+		return nil, err
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		buf := make([]byte, maxMessageSize)
+		for {
+			n, _, err := pc.ReadFrom(buf)
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					s.reportError(err)
+				}
+				return
+			}
+			s.dispatch(buf[:n])
+		}
+	}()
+	return pc.LocalAddr(), nil
+}
+
+// ListenTCP starts accepting stream connections on addr and returns the
+// bound address. Both octet counted and newline delimited framing are
+// accepted.
+func (s *Server) ListenTCP(addr string) (net.Addr, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return s.serveStream(ln)
+}
+
+// ListenTLS is like ListenTCP but requires TLS using the given config.
+func (s *Server) ListenTLS(addr string, config *tls.Config) (net.Addr, error) {
+	ln, err := tls.Listen("tcp", addr, config)
+	if err != nil {
+		return nil, err
+	}
+	return s.serveStream(ln)
+}
+
+func (s *Server) serveStream(ln net.Listener) (net.Addr, error) {
+	if err := s.track(ln); err != nil {
+		return nil, err
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					s.reportError(err)
+				}
+				return
+			}
+			s.Lock()
+			if s.closed {
+				s.Unlock()
+				c.Close()
+				return
+			}
+			s.conns[c] = struct{}{}
+			s.wg.Add(1)
+			s.Unlock()
+			go s.serveConn(c)
+		}
+	}()
+	return ln.Addr(), nil
+}
+
+func (s *Server) serveConn(c net.Conn) {
+	defer func() {
+		c.Close()
+		s.Lock()
+		delete(s.conns, c)
+		s.Unlock()
+		s.wg.Done()
+	}()
+
+	r := bufio.NewReaderSize(c, maxMessageSize)
+	for {
+		msg, err := readFrame(r)
+		if len(msg) > 0 {
+			s.dispatch(msg)
+		}
+		if err != nil {
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				s.reportError(err)
+			}
+			return
+		}
+	}
+}
+
+// readFrame reads one message from a stream, using octet counting
+// (RFC 6587) when the frame starts with a digit and newline
+// delimiting otherwise. Frames are limited to the size of r's buffer,
+// so that a peer cannot make the server buffer unbounded data.
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if first[0] >= '1' && first[0] <= '9' {
+		lenStr, err := r.ReadSlice(' ')
+		if err == bufio.ErrBufferFull {
+			return nil, fmt.Errorf("frame length exceeds %d bytes", r.Size())
+		}
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(string(lenStr[:len(lenStr)-1]))
+		if err != nil || n > maxMessageSize {
+			return nil, fmt.Errorf("invalid frame length %q", lenStr)
+		}
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	}
+	line, err := r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return nil, fmt.Errorf("frame exceeds %d bytes", r.Size())
+	}
+	// The slice is only valid until the next read.
+	return append([]byte(nil), line...), err
+}
+
+// Close stops all listeners, closes open connections and waits for
+// pending records to be handled.
+func (s *Server) Close() error {
+	s.Lock()
+	if s.closed {
+		s.Unlock()
+		return nil
+	}
+	s.closed = true
+	var errs []error
+	for _, l := range s.listeners {
+		if err := l.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for c := range s.conns {
+		c.Close()
+	}
+	s.Unlock()
+	s.wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Collector is a Handler that keeps every record it receives, which is
+// convenient for asserting what a logger sent.
+type Collector struct {
+	sync.Mutex
+	records []*Record
+	notify  chan struct{}
+}
+
+// NewCollector creates an empty collector.
+func NewCollector() *Collector {
+	return &Collector{notify: make(chan struct{}, 1)}
+}
+
+// Handle stores the record.
+func (c *Collector) Handle(r *Record) {
+	c.Lock()
+	c.records = append(c.records, r)
+	c.Unlock()
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Records returns a copy of the records received so far.
+func (c *Collector) Records() []*Record {
+	c.Lock()
+	defer c.Unlock()
+	return append([]*Record(nil), c.records...)
+}
+
+// Wait blocks until at least n records have been received or the
+// timeout expires, and returns the records received so far.
+func (c *Collector) Wait(n int, timeout time.Duration) ([]*Record, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		if recs := c.Records(); len(recs) >= n {
+			return recs, nil
+		}
+		select {
+		case <-c.notify:
+		case <-deadline.C:
+			recs := c.Records()
+			return recs, fmt.Errorf("received %d of %d records", len(recs), n)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package syslogd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"math/big"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Helper to create a server that collects records and is closed on cleanup
+func newTestServer(t *testing.T) (*Server, *Collector) {
+	t.Helper()
+	c := NewCollector()
+	s := NewServer(c)
+	t.Cleanup(func() { s.Close() })
+	return s, c
+}
+
+func expectRecords(t *testing.T, c *Collector, messages ...string) {
+	t.Helper()
+	recs, err := c.Wait(len(messages), 2*time.Second)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	for i, msg := range messages {
+		if recs[i].Message != msg {
+			t.Errorf("Expected message %q, got %q", msg, recs[i].Message)
+		}
+	}
+}
+
+func TestServer_UDP(t *testing.T) {
+	s, c := newTestServer(t)
+	addr, err := s.ListenUDP("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen: %v", err)
+	}
+
+	conn, err := net.Dial("udp", addr.String())
+	if err != nil {
+		t.Fatalf("Unable to dial: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "<14>1 - host app - - - over udp")
+	expectRecords(t, c, "over udp")
+}
+
+func TestServer_Unix(t *testing.T) {
+	s, c := newTestServer(t)
+	path := filepath.Join(t.TempDir(), "syslog.sock")
+	if _, err := s.ListenUnix(path); err != nil {
+		t.Fatalf("Unable to listen: %v", err)
+	}
+
+	conn, err := net.Dial("unixgram", path)
+	if err != nil {
+		t.Fatalf("Unable to dial: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "<14>Oct 11 22:14:15 app[1]: over unix")
+	expectRecords(t, c, "over unix")
+}
+
+func TestServer_TCPFraming(t *testing.T) {
+	s, c := newTestServer(t)
+	parseErrors := make(chan error, 10)
+	s.SetErrorHandler(func(err error) { parseErrors <- err })
+	addr, err := s.ListenTCP("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen: %v", err)
+	}
+
+	conn, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatalf("Unable to dial: %v", err)
+	}
+	defer conn.Close()
+
+	octet := "<14>1 - host app - - - counted\nwith newline"
+	fmt.Fprintf(conn, "<14>1 - host app - - - delimited\n")
+	fmt.Fprintf(conn, "%d %s", len(octet), octet)
+	fmt.Fprintf(conn, "<11>host app: last\n")
+	expectRecords(t, c, "delimited", "counted\nwith newline", "last")
+	select {
+	case err := <-parseErrors:
+		t.Errorf("Unexpected error: %v", err)
+	default:
+	}
+}
+
+func TestServer_OversizedFrame(t *testing.T) {
+	for _, frame := range []string{
+		strings.Repeat("a", maxMessageSize+1),
+		strings.Repeat("9", maxMessageSize+1),
+	} {
+		s, _ := newTestServer(t)
+		errs := make(chan error, 10)
+		s.SetErrorHandler(func(err error) { errs <- err })
+		addr, err := s.ListenTCP("127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Unable to listen: %v", err)
+		}
+		conn, err := net.Dial("tcp", addr.String())
+		if err != nil {
+			t.Fatalf("Unable to dial: %v", err)
+		}
+		defer conn.Close()
+
+		conn.Write([]byte(frame))
+		select {
+		case err := <-errs:
+			if !strings.Contains(err.Error(), "exceeds") {
+				t.Errorf("Expected an oversized frame error, got %v", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected the oversized frame %.10q... to be rejected", frame)
+		}
+		// The server drops the connection.
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if _, err := conn.Read(make([]byte, 1)); err == nil {
+			t.Error("Expected the connection to be closed")
+		}
+	}
+}
+
+func TestServer_TLS(t *testing.T) {
+	cert := newTestCertificate(t)
+	s, c := newTestServer(t)
+	addr, err := s.ListenTLS("127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatalf("Unable to listen: %v", err)
+	}
+
+	conn, err := tls.Dial("tcp", addr.String(), &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("Unable to dial: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "<14>1 - host app - - - over tls\n")
+	expectRecords(t, c, "over tls")
+}
+
+func TestServer_Close(t *testing.T) {
+	s, _ := newTestServer(t)
+	addr, err := s.ListenTCP("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen: %v", err)
+	}
+	conn, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatalf("Unable to dial: %v", err)
+	}
+	defer conn.Close()
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Unexpected error on Close: %v", err)
+	}
+	if _, err := s.ListenUDP("127.0.0.1:0"); err == nil {
+		t.Fatal("Expected error listening on a closed server")
+	}
+}
+
+type testPrinter struct {
+	lines []string
+}
+
+func (p *testPrinter) Noticef(format string, v ...any) {
+	p.lines = append(p.lines, "INF "+fmt.Sprintf(format, v...))
+}
+
+func (p *testPrinter) Warnf(format string, v ...any) {
+	p.lines = append(p.lines, "WRN "+fmt.Sprintf(format, v...))
+}
+
+func (p *testPrinter) Errorf(format string, v ...any) {
+	p.lines = append(p.lines, "ERR "+fmt.Sprintf(format, v...))
+}
+
+func (p *testPrinter) Debugf(format string, v ...any) {
+	p.lines = append(p.lines, "DBG "+fmt.Sprintf(format, v...))
+}
+
+func TestForward(t *testing.T) {
+	p := &testPrinter{}
+	h := Forward(p)
+	for _, msg := range []string{
+		"<8>1 - host app 12 - - emergency",
+		"<12>host app: warning",
+		"<14>1 - host app - - - info",
+		"<15>1 - - app - - - debug",
+	} {
+		r, err := Parse([]byte(msg))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		h.Handle(r)
+	}
+
+	expected := []string{
+		"ERR host app[12]: emergency",
+		"WRN host app: warning",
+		"INF host app: info",
+		"DBG app: debug",
+	}
+	if len(p.lines) != len(expected) {
+		t.Fatalf("Expected %d lines, got %v", len(expected), p.lines)
+	}
+	for i := range expected {
+		if p.lines[i] != expected[i] {
+			t.Errorf("Expected %q, got %q", expected[i], p.lines[i])
+		}
+	}
+}
+
+// Helper to generate a self signed certificate for the TLS listener
+func newTestCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Unable to generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Unable to create certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
@@ -0,0 +1,283 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"text/template"
+	"time"
+)
+
+const (
+	defaultWebhookQueueSize    = 100
+	defaultWebhookTimeout      = 5 * time.Second
+	defaultWebhookFlushTimeout = 5 * time.Second
+)
+
+// WebhookPayload is the data available to webhook body templates. It is
+// also the default JSON body.
+type WebhookPayload struct {
+	Message string    `json:"message"`
+	Level   string    `json:"level"`
+	Host    string    `json:"host"`
+	PID     int       `json:"pid"`
+	Time    time.Time `json:"time"`
+	Fields  Fields    `json:"fields,omitempty"`
+}
+
+// WebhookSink posts an HTTP request for every entry at or above a
+// minimum level, typically to raise alerts on errors. Requests are sent
+// asynchronously; repeated messages within the dedup window and
+// requests above the rate limit are dropped.
+type WebhookSink struct {
+	sync.Mutex
+	url          string
+	client       *http.Client
+	header       http.Header
+	minLevel     Level
+	tmpl         *template.Template
+	contentType  string
+	rateLimit    int
+	rateInterval time.Duration
+	windowStart  time.Time
+	windowCount  int
+	dedupWindow  time.Duration
+	lastSeen     map[string]time.Time
+	pruneAt      int
+	queue        chan *Entry
+	pending      sync.WaitGroup
+	done         chan struct{}
+	closed       bool
+}
+
+// NewWebhookSink creates a sink posting entries at or above minLevel to
+// the given URL.
+func NewWebhookSink(webhookURL string, minLevel Level) (*WebhookSink, error) {
+	u, err := url.Parse(webhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid webhook URL: %q", webhookURL)
+	}
+	s := &WebhookSink{
+		url:         webhookURL,
+		client:      &http.Client{Timeout: defaultWebhookTimeout},
+		header:      make(http.Header),
+		minLevel:    minLevel,
+		contentType: "application/json",
+		lastSeen:    make(map[string]time.Time),
+		queue:       make(chan *Entry, defaultWebhookQueueSize),
+		done:        make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// webhookFuncs are available in body templates. "json" renders a value
+// as JSON, which safely quotes strings embedded in JSON bodies.
+var webhookFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// SetTemplate sets a text/template used to render request bodies from a
+// WebhookPayload, e.g. `{"text": {{json .Message}}}` for Slack or
+// Mattermost incoming webhooks.
+func (s *WebhookSink) SetTemplate(body, contentType string) error {
+	tmpl, err := template.New("webhook").Funcs(webhookFuncs).Parse(body)
+	if err != nil {
+		return fmt.Errorf("invalid webhook template: %w", err)
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.tmpl = tmpl
+	if contentType != "" {
+		s.contentType = contentType
+	}
+	return nil
+}
+
+// SetRateLimit limits the number of requests to n per interval, further
+// entries in the same interval are dropped. A zero n disables the limit.
+func (s *WebhookSink) SetRateLimit(n int, interval time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+	s.rateLimit, s.rateInterval = n, interval
+}
+
+// SetDedupWindow drops entries with the same level and message as one
+// sent less than d ago.
+func (s *WebhookSink) SetDedupWindow(d time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+	s.dedupWindow = d
+}
+
+// SetHeader sets a header sent with every request.
+func (s *WebhookSink) SetHeader(key, value string) {
+	s.Lock()
+	defer s.Unlock()
+	s.header.Set(key, value)
+}
+
+func webhookDedupKey(e *Entry) string {
+	return e.Level.String() + "|" + e.Message
+}
+
+// allow applies the dedup window and rate limit. Lock must be held.
+func (s *WebhookSink) allow(e *Entry, now time.Time) bool {
+	if s.dedupWindow > 0 {
+		if last, ok := s.lastSeen[webhookDedupKey(e)]; ok && now.Sub(last) < s.dedupWindow {
+			return false
+		}
+	}
+	if s.rateLimit > 0 {
+		if now.Sub(s.windowStart) >= s.rateInterval {
+			s.windowStart, s.windowCount = now, 0
+		}
+		if s.windowCount >= s.rateLimit {
+			return false
+		}
+		s.windowCount++
+	}
+	return true
+}
+
+// remember starts the dedup window of a queued entry. Lock must be held.
+func (s *WebhookSink) remember(e *Entry, now time.Time) {
+	if s.dedupWindow <= 0 {
+		return
+	}
+	s.lastSeen[webhookDedupKey(e)] = now
+	// Forget expired messages so the map does not grow forever, scanning
+	// only when it doubled since the last scan.
+	if len(s.lastSeen) >= s.pruneAt {
+		for k, t := range s.lastSeen {
+			if now.Sub(t) >= s.dedupWindow {
+				delete(s.lastSeen, k)
+			}
+		}
+		s.pruneAt = max(2*len(s.lastSeen), 64)
+	}
+}
+
+// WriteEntry implements the Sink interface. Entries below the minimum
+// level, duplicates and entries over the rate limit are ignored.
+func (s *WebhookSink) WriteEntry(e *Entry) error {
+	if e.Level < s.minLevel {
+		return nil
+	}
+	// Entries carry the time of the logger's clock.
+	now := e.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+	s.Lock()
+	defer s.Unlock()
+	if s.closed || !s.allow(e, now) {
+		return nil
+	}
+	s.pending.Add(1)
+	select {
+	case s.queue <- e:
+		s.remember(e, now)
+		return nil
+	default:
+		s.pending.Done()
+		return fmt.Errorf("webhook queue full, entry dropped")
+	}
+}
+
+func (s *WebhookSink) run() {
+	defer close(s.done)
+	for e := range s.queue {
+		if err := s.send(e); err != nil {
+			countWriteError(ErrorSourceSink)
+			reportError(nil, ErrorSourceSink, fmt.Errorf("failed to send webhook: %w", err))
+		}
+		s.pending.Done()
+	}
+}
+
+func (s *WebhookSink) send(e *Entry) error {
+	payload := WebhookPayload{
+		Message: e.Message,
+		Level:   e.Level.String(),
+		Host:    e.Host,
+		PID:     e.PID,
+		Time:    e.Time,
+	}
+	if len(e.Fields) > 0 {
+		// Errors would otherwise be encoded as empty objects.
+		payload.Fields = make(Fields, len(e.Fields))
+		for k, v := range e.Fields {
+			if err, ok := v.(error); ok {
+				v = err.Error()
+			}
+			payload.Fields[k] = v
+		}
+	}
+	s.Lock()
+	tmpl, contentType, client := s.tmpl, s.contentType, s.client
+	header := s.header.Clone()
+	s.Unlock()
+
+	var body bytes.Buffer
+	if tmpl != nil {
+		if err := tmpl.Execute(&body, payload); err != nil {
+			return fmt.Errorf("unable to render webhook body: %w", err)
+		}
+	} else if err := json.NewEncoder(&body).Encode(payload); err != nil {
+		return fmt.Errorf("unable to encode webhook body: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	req.Header.Set("Content-Type", contentType)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Flush waits, for a bounded time, until queued requests have been sent.
+func (s *WebhookSink) Flush() error {
+	flushed := make(chan struct{})
+	go func() {
+		s.pending.Wait()
+		close(flushed)
+	}()
+	select {
+	case <-flushed:
+		return nil
+	case <-time.After(defaultWebhookFlushTimeout):
+		return fmt.Errorf("timed out flushing webhook requests")
+	}
+}
+
+// Close sends queued requests and stops the sender.
+func (s *WebhookSink) Close() error {
+	s.Lock()
+	if s.closed {
+		s.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.Unlock()
+	<-s.done
+	return nil
+}
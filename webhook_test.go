@@ -0,0 +1,176 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Helper webhook receiver recording request bodies.
+type testWebhookServer struct {
+	sync.Mutex
+	*httptest.Server
+	bodies       []string
+	contentTypes []string
+}
+
+func newTestWebhookServer(t *testing.T) *testWebhookServer {
+	t.Helper()
+	srv := &testWebhookServer{}
+	srv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		srv.Lock()
+		srv.bodies = append(srv.bodies, string(body))
+		srv.contentTypes = append(srv.contentTypes, r.Header.Get("Content-Type"))
+		srv.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (srv *testWebhookServer) Bodies() []string {
+	srv.Lock()
+	defer srv.Unlock()
+	return append([]string(nil), srv.bodies...)
+}
+
+func TestWebhookSink(t *testing.T) {
+	srv := newTestWebhookServer(t)
+	s, err := NewWebhookSink(srv.URL, LevelError)
+	if err != nil {
+		t.Fatalf("Failed to create webhook sink: %v", err)
+	}
+	defer s.Close()
+
+	s.WriteEntry(&Entry{Level: LevelWarn, Message: "ignored"})
+	s.WriteEntry(&Entry{Level: LevelError, Message: "db down", Host: "web1", PID: 42,
+		Fields: Fields{"err": errors.New("refused"), "attempt": 3}})
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+
+	bodies := srv.Bodies()
+	if len(bodies) != 1 {
+		t.Fatalf("Expected one webhook request, got %d", len(bodies))
+	}
+	var p WebhookPayload
+	if err := json.Unmarshal([]byte(bodies[0]), &p); err != nil {
+		t.Fatalf("Invalid payload %q: %v", bodies[0], err)
+	}
+	if p.Message != "db down" || p.Level != "error" || p.Host != "web1" || p.PID != 42 ||
+		p.Fields["err"] != "refused" || p.Fields["attempt"] != float64(3) {
+		t.Errorf("Unexpected payload: %+v", p)
+	}
+	if ct := srv.contentTypes[0]; ct != "application/json" {
+		t.Errorf("Expected JSON content type, got %q", ct)
+	}
+}
+
+func TestWebhookSink_Template(t *testing.T) {
+	srv := newTestWebhookServer(t)
+	s, err := NewWebhookSink(srv.URL, LevelError)
+	if err != nil {
+		t.Fatalf("Failed to create webhook sink: %v", err)
+	}
+	defer s.Close()
+	if err := s.SetTemplate(`{"text": {{json (printf "[%s] %s" .Level .Message)}}}`, ""); err != nil {
+		t.Fatalf("Failed to set template: %v", err)
+	}
+	if err := s.SetTemplate(`{{.Message`, ""); err == nil {
+		t.Error("Expected error for invalid template")
+	}
+
+	s.WriteEntry(&Entry{Level: LevelFatal, Message: `quote " here`})
+	s.Flush()
+
+	bodies := srv.Bodies()
+	if len(bodies) != 1 || bodies[0] != `{"text": "[fatal] quote \" here"}` {
+		t.Errorf("Unexpected templated body: %q", bodies)
+	}
+}
+
+func TestWebhookSink_DedupAndRateLimit(t *testing.T) {
+	srv := newTestWebhookServer(t)
+	s, err := NewWebhookSink(srv.URL, LevelError)
+	if err != nil {
+		t.Fatalf("Failed to create webhook sink: %v", err)
+	}
+	defer s.Close()
+	s.SetDedupWindow(time.Hour)
+	s.SetRateLimit(3, time.Hour)
+
+	for _, msg := range []string{"a", "a", "b", "a", "c", "d", "e"} {
+		s.WriteEntry(&Entry{Level: LevelError, Message: msg})
+	}
+	s.Flush()
+
+	bodies := srv.Bodies()
+	if len(bodies) != 3 {
+		t.Fatalf("Expected 3 requests after dedup and rate limit, got %d", len(bodies))
+	}
+	var got []string
+	for _, b := range bodies {
+		var p WebhookPayload
+		json.Unmarshal([]byte(b), &p)
+		got = append(got, p.Message)
+	}
+	if got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Errorf("Unexpected messages sent: %v", got)
+	}
+}
+
+func TestWebhookSink_RateLimitedNotDeduplicated(t *testing.T) {
+	srv := newTestWebhookServer(t)
+	s, err := NewWebhookSink(srv.URL, LevelError)
+	if err != nil {
+		t.Fatalf("Failed to create webhook sink: %v", err)
+	}
+	defer s.Close()
+	s.SetDedupWindow(time.Hour)
+	s.SetRateLimit(1, time.Minute)
+
+	// Entry times drive the windows, as they come from the logger's clock.
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.WriteEntry(&Entry{Level: LevelError, Message: "a", Time: start})
+	s.WriteEntry(&Entry{Level: LevelError, Message: "b", Time: start.Add(time.Second)})
+	s.WriteEntry(&Entry{Level: LevelError, Message: "b", Time: start.Add(2 * time.Minute)})
+	s.WriteEntry(&Entry{Level: LevelError, Message: "a", Time: start.Add(4 * time.Minute)})
+	s.Flush()
+
+	var got []string
+	for _, b := range srv.Bodies() {
+		var p WebhookPayload
+		json.Unmarshal([]byte(b), &p)
+		got = append(got, p.Message)
+	}
+	if len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("Expected the rate limited alert to be sent later, got %v", got)
+	}
+}
+
+func TestLoggerFlushSinks(t *testing.T) {
+	srv := newTestWebhookServer(t)
+	s, err := NewWebhookSink(srv.URL, LevelError)
+	if err != nil {
+		t.Fatalf("Failed to create webhook sink: %v", err)
+	}
+	defer s.Close()
+
+	l := NewSinkLogger(false, false, s)
+	l.Errorf("boom %d", 1)
+	l.flushSinks()
+	if bodies := srv.Bodies(); len(bodies) != 1 {
+		t.Errorf("Expected webhook to be sent before flushSinks returns, got %d", len(bodies))
+	}
+}
+
+func TestNewWebhookSink_InvalidURL(t *testing.T) {
+	if _, err := NewWebhookSink("hooks.example.com/x", LevelError); err == nil {
+		t.Error("Expected error for invalid URL")
+	}
+}